@@ -2,8 +2,10 @@ package cckat
 
 import (
 	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"math/big"
+	"strings"
 )
 
 type AddressType uint
@@ -12,85 +14,149 @@ type AddressType uint
 const (
 	P2PKH       AddressType = iota // Pay-to-pubkey-hash
 	P2PKHUncomp                    // Pay-to-pubkey-hash (uncompressed pubkey)
-	P2SH                           // Pay-to-script-hash
+	P2SH                           // Pay-to-script-hash (P2SH-P2WPKH for a public key, see P2SHP2WPKH)
 	P2WPKH                         // Pay-to-witness-pubkey-hash
 	P2TR                           // Pay-to-taproot
 	ETH                            // Ethereum address (mixed-case checksum)
+	P2SHP2WPKH                     // Pay-to-witness-pubkey-hash nested in Pay-to-script-hash
+	P2WSH                          // Pay-to-witness-script-hash (see MultisigAddress, ScriptAddressP2WSH)
+	P2SHP2WSH                      // Pay-to-witness-script-hash nested in Pay-to-script-hash
 	MaxType
 )
 
-// The array of functions of address. All functions accept a compressed or uncompressed public key.
-var addresses = [MaxType]func([]byte) (string, error){
-	P2PKH:       GetAddressP2PKH,
-	P2PKHUncomp: GetAddressP2PKHUncomp,
-	P2SH:        GetAddressP2SH,
-	P2WPKH:      GetAddressP2WPKH,
-	P2TR:        GetAddressP2TR,
-	ETH:         GetAddressETH,
+var (
+	InvAddrCSum   = errors.New("invalid address checksum")
+	InvAddrLen    = errors.New("invalid address length")
+	InvAddrVer    = errors.New("invalid address version")
+	UnsupAddrType = errors.New("unsupported address type")
+)
+
+// The array of functions of address. All functions accept a public key in any format accepted by ParsePubKey.
+// The script address types (P2WSH, P2SHP2WSH) have no function.
+var addresses = [MaxType]func([]byte, *Network) (string, error){
+	P2PKH:       getAddressP2PKHComp,
+	P2PKHUncomp: getAddressP2PKHUncomp,
+	P2SH:        getAddressP2SHP2WPKH,
+	P2WPKH:      getAddressP2WPKH,
+	P2TR:        getAddressP2TR,
+	ETH:         getAddressETH,
+	P2SHP2WPKH:  getAddressP2SHP2WPKH,
+}
+
+// GetAddress returns the address of the type t for the network n.
+func GetAddress(pubKey []byte, t AddressType, n *Network) (string, error) {
+	if !checkAddressType(t) {
+		return "", InvAddrType
+	}
+	return addresses[t](pubKey, n)
 }
 
 // GetAddressP2PKH returns the Pay-to-pubkey-hash Bitcoin address (compressed pubkey).
 func GetAddressP2PKH(pubKey []byte) (string, error) {
-	return getAddressP2PKH(pubKey, true)
+	return getAddressP2PKH(pubKey, true, BTCMain)
 }
 
 // GetAddressP2PKHUncomp returns the Pay-to-pubkey-hash Bitcoin address (uncompressed pubkey).
 func GetAddressP2PKHUncomp(pubKey []byte) (string, error) {
-	return getAddressP2PKH(pubKey, false)
+	return getAddressP2PKH(pubKey, false, BTCMain)
+}
+
+func getAddressP2PKHComp(pubKey []byte, n *Network) (string, error) {
+	return getAddressP2PKH(pubKey, true, n)
 }
 
-// getAddressP2PKH returns the Pay-to-pubkey-hash Bitcoin address (compressed or uncompressed pubkey).
-func getAddressP2PKH(pubKey []byte, comp bool) (string, error) {
+func getAddressP2PKHUncomp(pubKey []byte, n *Network) (string, error) {
+	return getAddressP2PKH(pubKey, false, n)
+}
+
+// getAddressP2PKH returns the Pay-to-pubkey-hash address (compressed or uncompressed pubkey).
+func getAddressP2PKH(pubKey []byte, comp bool, n *Network) (string, error) {
 	p, err := PubKeyCompUncomp(pubKey, comp)
 	if err != nil {
 		return "", err
 	}
-	v := make([]byte, 1, 25)
-	//v[0] = 0
-	v = append(v, HashPubKey(p)...)
-	//checksum := checksum(v)
-	return string(Base58Encode(append(v, checksum(v)...))), nil
+	return base58Check(n.PubKeyHash, HashPubKey(p)), nil
 }
 
-// GetAddressP2SH returns the Pay-to-script-hash Bitcoin address.
+// GetAddressP2SH returns the P2SH-P2WPKH Bitcoin address.
+//
+// Deprecated: use GetAddressP2SHP2WPKH or ScriptAddressP2SH for an arbitrary redeem script.
 func GetAddressP2SH(pubKey []byte) (string, error) {
-	p, err := PubKeyCompUncomp(pubKey, true)
+	return getAddressP2SHP2WPKH(pubKey, BTCMain)
+}
+
+// GetAddressP2SHP2WPKH returns the Pay-to-witness-pubkey-hash nested in Pay-to-script-hash Bitcoin address.
+func GetAddressP2SHP2WPKH(pubKey []byte) (string, error) {
+	return getAddressP2SHP2WPKH(pubKey, BTCMain)
+}
+
+func getAddressP2SHP2WPKH(pubKey []byte, n *Network) (string, error) {
+	if n.Bech32HRP == "" {
+		return "", NoSegwit
+	}
+	s, err := RedeemScriptP2SHP2WPKH(pubKey)
 	if err != nil {
 		return "", err
 	}
-	v := make([]byte, 2, 22)
-	v = []byte{0x00, 0x14}
-	w := make([]byte, 1, 25)
-	w[0] = 0x05
-	w = append(w, HashPubKey(append(v, HashPubKey(p)...))...)
-	w = append(w, checksum(w)...)
-	return string(Base58Encode(w)), nil
+	return ScriptAddressP2SH(s, n)
+}
+
+// RedeemScriptP2SHP2WPKH returns the redeem script of the P2SH-P2WPKH address: OP_0 <hash160 of the compressed pubKey>.
+func RedeemScriptP2SHP2WPKH(pubKey []byte) ([]byte, error) {
+	p, err := PubKeyCompUncomp(pubKey, true)
+	if err != nil {
+		return nil, err
+	}
+	return witnessScript(0, HashPubKey(p)), nil
+}
+
+// ScriptAddressP2SH returns the Pay-to-script-hash address of the redeem script for the network n.
+// Returns InvScriptLen if the script is empty or longer than 520 bytes (it could not be spent).
+func ScriptAddressP2SH(redeemScript []byte, n *Network) (string, error) {
+	if len(redeemScript) == 0 || len(redeemScript) > maxScriptElementSize {
+		return "", InvScriptLen
+	}
+	return base58Check(n.ScriptHash, HashPubKey(redeemScript)), nil
 }
 
 // GetAddressP2WPKH returns the Pay-to-witness-pubkey-hash Bitcoin address.
 func GetAddressP2WPKH(pubKey []byte) (string, error) {
+	return getAddressP2WPKH(pubKey, BTCMain)
+}
+
+func getAddressP2WPKH(pubKey []byte, n *Network) (string, error) {
+	if n.Bech32HRP == "" {
+		return "", NoSegwit
+	}
 	p, err := PubKeyCompUncomp(pubKey, true)
 	if err != nil {
 		return "", err
 	}
-	return Bech32mencode(HashPubKey(p), "bc", 0), nil
+	return Bech32mencode(HashPubKey(p), n.Bech32HRP, 0), nil
 }
 
 // GetAddressP2TR returns the Pay-to-taproot Bitcoin address with pubKey as internal key.
 func GetAddressP2TR(pubKey []byte) (string, error) {
-	pk, err := PubKeyCompUncomp(pubKey, false)
+	return getAddressP2TR(pubKey, BTCMain)
+}
+
+func getAddressP2TR(pubKey []byte, n *Network) (string, error) {
+	if n.Bech32HRP == "" {
+		return "", NoSegwit
+	}
+	q, err := TapOutputKey(pubKey, nil)
 	if err != nil {
 		return "", err
 	}
-	x := new(big.Int).SetBytes(pk[1:33])
-	y := new(big.Int).SetBytes(pk[33:65])
-	if y.Bit(0) == 1 {
-		y.Sub(secp256k1.P, y)
-	}
-	tw := taggedHash("TapTweak", bytesFull(x))
-	tx, ty := secp256k1.ScalarBaseMult(tw)
-	qx, _ := secp256k1.Add(x, y, tx, ty)
-	return Bech32mencode(bytesFull(qx), "bc", 1), nil
+	return Bech32mencode(q, n.Bech32HRP, 1), nil
+}
+
+// base58Check returns the Base58Check encoding of the version byte ver followed by payload.
+func base58Check(ver byte, payload []byte) string {
+	v := make([]byte, 1, len(payload)+5)
+	v[0] = ver
+	v = append(v, payload...)
+	return string(Base58Encode(append(v, checksum(v)...)))
 }
 
 func taggedHash(tag string, b []byte) []byte {
@@ -102,16 +168,101 @@ func taggedHash(tag string, b []byte) []byte {
 
 // GetAddressETH returns the Ethereum address (mixed-case checksum).
 func GetAddressETH(pubKey []byte) (string, error) {
+	return getAddressETH(pubKey, nil)
+}
+
+// getAddressETH returns the Ethereum address. The network is ignored.
+func getAddressETH(pubKey []byte, _ *Network) (string, error) {
 	p, err := PubKeyCompUncomp(pubKey, false)
 	if err != nil {
 		return "", err
 	}
-	r := []byte(fmt.Sprintf("%x", Keccak256Hash(p[1:])[12:]))
+	return "0x" + ethChecksum(fmt.Sprintf("%x", Keccak256Hash(p[1:])[12:])), nil
+}
+
+// ethChecksum returns the mixed-case checksum encoding (EIP-55) of the lower case hex address a (without 0x).
+func ethChecksum(a string) string {
+	r := []byte(a)
 	rh := Keccak256Hash(r)
 	for i := 0; i < 40; i++ {
 		if r[i] > 64 {
 			r[i] ^= (rh[i/2] >> (7 - (i&1)*4) & 1) << 5
 		}
 	}
-	return "0x" + string(r), nil
+	return string(r)
+}
+
+// ParseAddress decodes and validates the Bitcoin mainnet or Ethereum address a. See Network.ParseAddress.
+func ParseAddress(a string) (AddressType, []byte, error) {
+	return BTCMain.ParseAddress(a)
+}
+
+// ParseAddress decodes and validates the address a of the network n or the Ethereum address.
+// Returns the address type and the decoded data:
+// the hash160 of the public key (P2PKH) or of the script (P2SH), the witness program (P2WPKH, P2WSH, P2TR)
+// or 20 bytes of the Ethereum address (ETH).
+// P2PKH is returned for both compressed and uncompressed pubkey addresses since they are indistinguishable.
+func (n *Network) ParseAddress(a string) (AddressType, []byte, error) {
+	a = strings.TrimSpace(a)
+	switch {
+	case strings.HasPrefix(a, "0x") || strings.HasPrefix(a, "0X"):
+		return parseAddressETH(a[2:])
+	case n.Bech32HRP != "" && strings.HasPrefix(strings.ToLower(a), n.Bech32HRP+"1"):
+		return n.parseAddressSegwit(a)
+	}
+	return n.parseAddressBase58(a)
+}
+
+func (n *Network) parseAddressBase58(a string) (AddressType, []byte, error) {
+	d, err := Base58Decode([]byte(a))
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(d) != 25 {
+		return 0, nil, InvAddrLen
+	}
+	if string(checksum(d[:21])) != string(d[21:]) {
+		return 0, nil, InvAddrCSum
+	}
+	switch d[0] {
+	case n.PubKeyHash:
+		return P2PKH, d[1:21], nil
+	case n.ScriptHash:
+		return P2SH, d[1:21], nil
+	}
+	return 0, nil, InvAddrVer
+}
+
+func (n *Network) parseAddressSegwit(a string) (AddressType, []byte, error) {
+	hrp, ver, prog, err := Bech32Decode(a)
+	if err != nil {
+		return 0, nil, err
+	}
+	if hrp != n.Bech32HRP {
+		return 0, nil, InvHRP
+	}
+	switch {
+	case ver == 0 && len(prog) == 20:
+		return P2WPKH, prog, nil
+	case ver == 0 && len(prog) == 32:
+		return P2WSH, prog, nil
+	case ver == 1 && len(prog) == 32:
+		return P2TR, prog, nil
+	}
+	return 0, nil, UnsupAddrType
+}
+
+func parseAddressETH(a string) (AddressType, []byte, error) {
+	if len(a) != 40 {
+		return 0, nil, InvAddrLen
+	}
+	if !isHex(a) {
+		return 0, nil, InvHexStr
+	}
+	l := strings.ToLower(a)
+	if a != l && a != strings.ToUpper(a) && a != ethChecksum(l) {
+		return 0, nil, InvAddrCSum
+	}
+	b, _ := hex.DecodeString(l)
+	return ETH, b, nil
 }
@@ -0,0 +1,84 @@
+package cckat
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// The valid segwit addresses of BIP350 with their scriptPubKeys.
+func TestParseAddressSegwit(t *testing.T) {
+	for _, c := range []struct {
+		addr   string
+		n      *Network
+		script string
+		at     AddressType
+		err    error
+	}{
+		{"BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4", BTCMain,
+			"0014751e76e8199196d454941c45d1b3a323f1433bd6", P2WPKH, nil},
+		{"tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7", BTCTest,
+			"00201863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262", P2WSH, nil},
+		{"bc1pw508d6qejxtdg4y5r3zarvary0c5xw7kw508d6qejxtdg4y5r3zarvary0c5xw7kt5nd6y", BTCMain,
+			"5128751e76e8199196d454941c45d1b3a323f1433bd6751e76e8199196d454941c45d1b3a323f1433bd6", 0, UnsupAddrType},
+		{"BC1SW50QGDZ25J", BTCMain, "6002751e", 0, UnsupAddrType},
+		{"bc1zw508d6qejxtdg4y5r3zarvaryvaxxpcs", BTCMain, "5210751e76e8199196d454941c45d1b3a323", 0, UnsupAddrType},
+		{"tb1qqqqqp399et2xygdj5xreqhjjvcmzhxw4aywxecjdzew6hylgvsesrxh6hy", BTCTest,
+			"0020000000c4a5cad46221b2a187905e5266362b99d5e91c6ce24d165dab93e86433", P2WSH, nil},
+		{"tb1pqqqqp399et2xygdj5xreqhjjvcmzhxw4aywxecjdzew6hylgvsesf3hn0c", BTCTest,
+			"5120000000c4a5cad46221b2a187905e5266362b99d5e91c6ce24d165dab93e86433", P2TR, nil},
+		{"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", BTCMain,
+			"512079be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798", P2TR, nil},
+	} {
+		hrp, ver, prog, err := Bech32Decode(c.addr)
+		if err != nil || hrp != c.n.Bech32HRP {
+			t.Errorf("%s: Bech32Decode %s, %v", c.addr, hrp, err)
+			continue
+		}
+		if s := hex.EncodeToString(witnessScript(byte(ver), prog)); s != c.script {
+			t.Errorf("%s: script %s, want %s", c.addr, s, c.script)
+		}
+		at, p, err := c.n.ParseAddress(c.addr)
+		if err != c.err {
+			t.Errorf("%s: %v, want %v", c.addr, err, c.err)
+		} else if err == nil && (at != c.at || hex.EncodeToString(p) != c.script[4:]) {
+			t.Errorf("%s: got %d %x, want %d", c.addr, at, p, c.at)
+		}
+	}
+}
+
+// The invalid segwit addresses of BIP173 and BIP350.
+func TestParseAddressSegwitInvalid(t *testing.T) {
+	for _, c := range []struct {
+		name, addr string
+		n          *Network
+		err        error
+	}{
+		// BIP350
+		{"v1 hrp", "tc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq5zuyut", BTCMain, nil},
+		{"v1 Bech32", "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqh2y7hd", BTCMain, InvWitConst},
+		{"v2 Bech32", "tb1z0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqglt7rf", BTCTest, InvWitConst},
+		{"v16 Bech32", "BC1S0XLXVLHEMJA6C4DQV22UAPCTQUPFHLXM9H8Z3K2E72Q4K9HCZ7VQ54WELL", BTCMain, InvWitConst},
+		{"v0 Bech32m", "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kemeawh", BTCMain, InvWitConst},
+		{"v0 Bech32m 32", "tb1q0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq24jc47", BTCTest, InvWitConst},
+		{"checksum char", "bc1p38j9r5y49hruaue7wxjce0updqjuyyx0kh56v8s25huc6995vvpql3jow4", BTCMain, InvChar},
+		{"v17", "BC130XLXVLHEMJA6C4DQV22UAPCTQUPFHLXM9H8Z3K2E72Q4K9HCZ7VQ7ZWS8R", BTCMain, InvWitVer},
+		{"v1 1 byte", "bc1pw5dgrnzv", BTCMain, InvWitProg},
+		{"v1 41 bytes", "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7v8n0nx0muaewav253zgeav", BTCMain, InvWitProg},
+		{"v0 16 bytes", "BC1QR508D6QEJXTDG4Y5R3ZARVARYV98GJ9P", BTCMain, InvWitProg},
+		{"mixed case", "tb1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq47Zagq", BTCTest, MixedCase},
+		{"padding > 4 bits", "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7v07qwwzcrf", BTCMain, InvPadding},
+		{"non-zero padding", "tb1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vpggkg4j", BTCTest, InvPadding},
+		{"empty data", "bc1gmk9yu", BTCMain, InvWitProg},
+		// BIP173
+		{"v0 hrp", "tc1qw508d6qejxtdg4y5r3zarvary0c5xw7kg3g4ty", BTCTest, nil},
+		{"v0 checksum", "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t5", BTCMain, InvAddrCSum},
+		{"v0 mixed case", "tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sL5k7", BTCTest, MixedCase},
+		{"v0 non-zero padding", "tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3pjxtptv", BTCTest, InvPadding},
+		{"wrong network", "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", BTCTest, nil},
+	} {
+		at, p, err := c.n.ParseAddress(c.addr)
+		if err == nil || (c.err != nil && err != c.err) {
+			t.Errorf("%s: got %d %x, %v, want %v", c.name, at, p, err, c.err)
+		}
+	}
+}
@@ -1,9 +1,24 @@
 package cckat
 
-import "strings"
+import (
+	"errors"
+	"slices"
+	"strings"
+)
 
 const charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
 
+var (
+	InvChar     = errors.New("invalid character")
+	InvHRP      = errors.New("invalid human-readable part")
+	InvPadding  = errors.New("invalid padding")
+	InvWitVer   = errors.New("invalid witness version")
+	InvWitProg  = errors.New("invalid witness program length")
+	InvWitConst = errors.New("wrong Bech32 variant for witness version")
+	MixedCase   = errors.New("mixed case")
+	TooManyErr  = errors.New("too many errors to locate")
+)
+
 var gen = []int{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
 
 // Bech32mencode encodes a segwit (Bech32 BIP 0173 / Bech32m BIP 0350) address.
@@ -55,12 +70,17 @@ func Convbits85(p []byte) []int {
 func polymod(values []int) int {
 	chk := 1
 	for _, v := range values {
-		top := chk >> 25
-		chk = (chk&0x1ffffff)<<5 ^ v
-		for i := 0; i < 5; i++ {
-			if (top>>uint(i))&1 == 1 {
-				chk ^= gen[i]
-			}
+		chk = polymodStep(chk, v)
+	}
+	return chk
+}
+
+func polymodStep(chk, v int) int {
+	top := chk >> 25
+	chk = (chk&0x1ffffff)<<5 ^ v
+	for i := 0; i < 5; i++ {
+		if (top>>uint(i))&1 == 1 {
+			chk ^= gen[i]
 		}
 	}
 	return chk
@@ -90,3 +110,196 @@ func csum(hrp string, data []int, mconst int) []int {
 	}
 	return ret
 }
+
+// Bech32Decode decodes a segwit (Bech32 BIP 0173 / Bech32m BIP 0350) address.
+// Returns the human-readable part (lower case), the witness version and the witness program.
+func Bech32Decode(addr string) (hrp string, ver int, prog []byte, err error) {
+	hrp, data, err := bech32Parse(addr)
+	if err != nil {
+		return
+	}
+	var b32const int
+	switch polymod(append(hrpExpand(hrp), data...)) {
+	case 1:
+		b32const = 1
+	case 0x2bc830a3:
+		b32const = 0x2bc830a3
+	default:
+		return "", 0, nil, InvAddrCSum
+	}
+	data = data[:len(data)-6]
+	if len(data) < 1 {
+		return "", 0, nil, InvWitProg
+	}
+	ver = data[0]
+	if ver > 16 {
+		return "", 0, nil, InvWitVer
+	}
+	if (ver == 0) != (b32const == 1) {
+		return "", 0, nil, InvWitConst
+	}
+	prog, err = Convbits58(data[1:])
+	if err != nil {
+		return "", 0, nil, err
+	}
+	if len(prog) < 2 || len(prog) > 40 || (ver == 0 && len(prog) != 20 && len(prog) != 32) {
+		return "", 0, nil, InvWitProg
+	}
+	return hrp, ver, prog, nil
+}
+
+// bech32Parse checks the length, the case and the characters of the Bech32 string s.
+// Returns the human-readable part and the data part (including the checksum) in lower case.
+func bech32Parse(s string) (hrp string, data []int, err error) {
+	if len(s) > 90 {
+		return "", nil, InvAddrLen
+	}
+	if strings.ToLower(s) != s && strings.ToUpper(s) != s {
+		return "", nil, MixedCase
+	}
+	s = strings.ToLower(s)
+	pos := strings.LastIndexByte(s, '1')
+	if pos < 1 || pos+7 > len(s) {
+		return "", nil, InvAddrLen
+	}
+	hrp = s[:pos]
+	for _, c := range []byte(hrp) {
+		if c < 33 || c > 126 {
+			return "", nil, InvHRP
+		}
+	}
+	data = make([]int, len(s)-pos-1)
+	for i, c := range []byte(s[pos+1:]) {
+		d := strings.IndexByte(charset, c)
+		if d < 0 {
+			return "", nil, InvChar
+		}
+		data[i] = d
+	}
+	return hrp, data, nil
+}
+
+// Convbits58 converts an int slice (5 bits) to byte slice (8 bits).
+// Returns InvPadding if the padding is longer than 4 bits or non-zero.
+func Convbits58(d []int) ([]byte, error) {
+	ret := make([]byte, 0, len(d)*5/8)
+	acc, bits := 0, 0
+	for _, v := range d {
+		if v < 0 || v > 31 {
+			return nil, InvChar
+		}
+		acc = (acc<<5 | v) & 0xfff
+		bits += 5
+		if bits >= 8 {
+			bits -= 8
+			ret = append(ret, byte(acc>>bits))
+		}
+	}
+	if bits >= 5 || acc&(1<<bits-1) != 0 {
+		return nil, InvPadding
+	}
+	return ret, nil
+}
+
+// Bech32LocateErrors returns the positions in s of up to two substituted characters in the data part
+// of the Bech32 or Bech32m string s (e.g. a segwit address with a typo).
+// Returns nil, nil if the checksum is valid and TooManyErr if the errors can not be located.
+// Characters which are not in the Bech32 charset are always reported. Errors in the human-readable part are not located.
+func Bech32LocateErrors(s string) ([]int, error) {
+	if len(s) > 90 {
+		return nil, InvAddrLen
+	}
+	if strings.ToLower(s) != s && strings.ToUpper(s) != s {
+		return nil, MixedCase
+	}
+	s = strings.ToLower(s)
+	pos := strings.LastIndexByte(s, '1')
+	if pos < 1 || pos+7 > len(s) {
+		return nil, InvAddrLen
+	}
+	hrp := s[:pos]
+	data := make([]int, len(s)-pos-1)
+	var inv []int
+	for i, c := range []byte(s[pos+1:]) {
+		d := strings.IndexByte(charset, c)
+		if d < 0 {
+			inv = append(inv, i)
+			d = 0
+		}
+		data[i] = d
+	}
+	res := polymod(append(hrpExpand(hrp), data...))
+	consts := []int{0x2bc830a3, 1}
+	if data[0] == 0 {
+		consts[0], consts[1] = 1, 0x2bc830a3
+	}
+	var found []int
+	for _, c := range consts {
+		if res == c && len(inv) == 0 {
+			return nil, nil
+		}
+		e, ok := locateErrors(res^c, len(data))
+		if !ok {
+			continue
+		}
+		e = mergePositions(inv, e)
+		if len(e) <= 2 && (found == nil || len(e) < len(found)) {
+			found = e
+		}
+	}
+	if found == nil {
+		return nil, TooManyErr
+	}
+	for i := range found {
+		found[i] += pos + 1
+	}
+	return found, nil
+}
+
+// locateErrors returns the positions of at most two errors in the data of length l
+// which produce the checksum residue syndrome.
+func locateErrors(syndrome, l int) ([]int, bool) {
+	// errs[i][d] is the residue produced by the error d at the position i.
+	errs := make([][32]int, l)
+	for i := l - 1; i >= 0; i-- {
+		for d := 1; d < 32; d++ {
+			if i == l-1 {
+				errs[i][d] = d
+			} else {
+				errs[i][d] = polymodStep(errs[i+1][d], 0)
+			}
+		}
+	}
+	if syndrome == 0 {
+		return nil, true
+	}
+	m := make(map[int]int, l*31)
+	for i := range errs {
+		for d := 1; d < 32; d++ {
+			if errs[i][d] == syndrome {
+				return []int{i}, true
+			}
+			m[errs[i][d]] = i
+		}
+	}
+	for i := range errs {
+		for d := 1; d < 32; d++ {
+			if j, ok := m[syndrome^errs[i][d]]; ok && j > i {
+				return []int{i, j}, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// mergePositions returns the sorted union of the positions a and b.
+func mergePositions(a, b []int) []int {
+	res := append([]int(nil), a...)
+	for _, p := range b {
+		if !slices.Contains(res, p) {
+			res = append(res, p)
+		}
+	}
+	slices.Sort(res)
+	return res
+}
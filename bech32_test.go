@@ -0,0 +1,49 @@
+package cckat
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBech32LocateErrors(t *testing.T) {
+	const (
+		v0 = "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"
+		v1 = "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0"
+	)
+	sub := func(s string, pos ...int) string {
+		b := []byte(s)
+		for _, p := range pos {
+			if b[p] == 'q' {
+				b[p] = 'p'
+			} else {
+				b[p] = 'q'
+			}
+		}
+		return string(b)
+	}
+	for _, c := range []struct {
+		name string
+		s    string
+		want []int
+		err  error
+	}{
+		{"valid v0", v0, nil, nil},
+		{"valid v1", v1, nil, nil},
+		{"valid upper case", "BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4", nil, nil},
+		{"v0 one error", sub(v0, 10), []int{10}, nil},
+		{"v0 two errors", sub(v0, 5, 40), []int{5, 40}, nil},
+		{"v1 one error", sub(v1, 61), []int{61}, nil},
+		{"v1 two errors", sub(v1, 4, 30), []int{4, 30}, nil},
+		{"v0 checksum error", sub(v0, 39, 41), []int{39, 41}, nil},
+		{"invalid character", v0[:20] + "b" + v0[21:], []int{20}, nil},
+		{"invalid character and error", sub(v0[:20]+"b"+v0[21:], 30), []int{20, 30}, nil},
+		{"too many errors", sub(v0, 5, 12, 20, 33), nil, TooManyErr},
+		{"mixed case", "bc1qW508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", nil, MixedCase},
+		{"no separator", "bcqw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", nil, InvAddrLen},
+	} {
+		got, err := Bech32LocateErrors(c.s)
+		if err != c.err || !slices.Equal(got, c.want) {
+			t.Errorf("%s: got %v, %v, want %v, %v", c.name, got, err, c.want, c.err)
+		}
+	}
+}
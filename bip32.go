@@ -0,0 +1,343 @@
+package cckat
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// HardenedKeyStart is the index of the first hardened child key (BIP32).
+const HardenedKeyStart uint32 = 0x80000000
+
+var (
+	InvSeedLen  = errors.New("seed length must be between 128 and 512 bits")
+	InvExtKey   = errors.New("invalid extended key")
+	InvExtCSum  = errors.New("invalid extended key checksum")
+	InvPath     = errors.New("invalid derivation path")
+	InvChild    = errors.New("invalid child key, use the next index")
+	HardenedPub = errors.New("cannot derive a hardened child from a public key")
+	NotPrivate  = errors.New("extended key is not private")
+	MaxDepth    = errors.New("maximum depth of derivation exceeded")
+)
+
+// ExtendedKey is a BIP32 extended private or public key.
+// See https://github.com/bitcoin/bips/blob/master/bip-0032.mediawiki
+type ExtendedKey struct {
+	key       PrKey // the private key, set only for private extended keys
+	pub       PublicKey
+	chainCode []byte
+	parentFP  []byte
+	depth     byte
+	childNum  uint32
+	private   bool
+	net       *Network
+}
+
+// NewMasterKey returns the master extended private key generated from seed (16 - 64 bytes) for the network n
+// (BTCMain if n is nil).
+func NewMasterKey(seed []byte, n *Network) (*ExtendedKey, error) {
+	if len(seed) < 16 || len(seed) > 64 {
+		return nil, InvSeedLen
+	}
+	if n == nil {
+		n = BTCMain
+	}
+	m := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	m.Write(seed)
+	i := m.Sum(nil)
+	e := &ExtendedKey{chainCode: i[32:], parentFP: make([]byte, 4), private: true, net: n}
+	if _, err := e.key.SetBytes(i[:32]); err != nil {
+		return nil, InvSeedLen
+	}
+	e.key.net = n
+	e.pub = e.key.PubK()
+	return e, nil
+}
+
+// Child returns the child key with the index i (CKDpriv for private keys, CKDpub for public keys).
+// Indexes from HardenedKeyStart are hardened.
+func (e *ExtendedKey) Child(i uint32) (*ExtendedKey, error) {
+	if e.private {
+		return e.CKDpriv(i)
+	}
+	return e.CKDpub(i)
+}
+
+// CKDpriv returns the child private key with the index i. Returns NotPrivate for public keys.
+func (e *ExtendedKey) CKDpriv(i uint32) (*ExtendedKey, error) {
+	if !e.private {
+		return nil, NotPrivate
+	}
+	if e.depth == 255 {
+		return nil, MaxDepth
+	}
+	var data []byte
+	if i >= HardenedKeyStart {
+		data = append([]byte{0x00}, bytesFull(&e.key.k)...)
+	} else {
+		data = e.pub.Compressed()
+	}
+	il, ir := e.hmac(data, i)
+	if il.Cmp(secp256k1.N) >= 0 {
+		return nil, InvChild
+	}
+	il.Add(il, &e.key.k)
+	il.Mod(il, secp256k1.N)
+	c := e.child(ir, i)
+	if _, err := c.key.Set(*il); err != nil {
+		return nil, InvChild
+	}
+	c.key.net = e.net
+	c.pub = c.key.PubK()
+	c.private = true
+	return c, nil
+}
+
+// CKDpub returns the child public key with the index i.
+// For private keys the public key of CKDpriv(i) is returned. Returns HardenedPub for hardened indexes of public keys.
+func (e *ExtendedKey) CKDpub(i uint32) (*ExtendedKey, error) {
+	if e.private {
+		c, err := e.CKDpriv(i)
+		if err != nil {
+			return nil, err
+		}
+		return c.Neuter(), nil
+	}
+	if i >= HardenedKeyStart {
+		return nil, HardenedPub
+	}
+	if e.depth == 255 {
+		return nil, MaxDepth
+	}
+	il, ir := e.hmac(e.pub.Compressed(), i)
+	if il.Cmp(secp256k1.N) >= 0 {
+		return nil, InvChild
+	}
+	px, py := e.pub.Point()
+	tx, ty := secp256k1.ScalarBaseMult(il.Bytes())
+	x, y := secp256k1.Add(px, py, tx, ty)
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return nil, InvChild
+	}
+	c := e.child(ir, i)
+	c.pub, _ = NewPublicKey(x, y)
+	return c, nil
+}
+
+// hmac returns I = HMAC-SHA512(chain code, data || ser32(i)) as int(IL) and IR.
+func (e *ExtendedKey) hmac(data []byte, i uint32) (*big.Int, []byte) {
+	m := hmac.New(sha512.New, e.chainCode)
+	m.Write(data)
+	m.Write(binary.BigEndian.AppendUint32(nil, i))
+	r := m.Sum(nil)
+	return new(big.Int).SetBytes(r[:32]), r[32:]
+}
+
+// child returns the child of e with the chain code c and the index i without the key.
+func (e *ExtendedKey) child(c []byte, i uint32) *ExtendedKey {
+	return &ExtendedKey{chainCode: c, parentFP: e.Fingerprint(), depth: e.depth + 1, childNum: i, net: e.net}
+}
+
+// Derive returns the key derived from e by the path like "m/84'/0'/0'/0/5". See ParsePath.
+func (e *ExtendedKey) Derive(path string) (*ExtendedKey, error) {
+	p, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return e.DerivePath(p)
+}
+
+// DerivePath returns the key derived from e by the sequence of indexes p.
+func (e *ExtendedKey) DerivePath(p []uint32) (*ExtendedKey, error) {
+	var err error
+	for _, i := range p {
+		if e, err = e.Child(i); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// ParsePath parses the derivation path like "m/84'/0'/0'/0/5" and returns the sequence of indexes.
+// The leading "m" is optional, hardened indexes are marked with ', h or H.
+func ParsePath(path string) ([]uint32, error) {
+	path = strings.TrimSpace(path)
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "m"), "/")
+	if path == "" {
+		return []uint32{}, nil
+	}
+	parts := strings.Split(path, "/")
+	res := make([]uint32, len(parts))
+	for j, p := range parts {
+		var h uint32
+		if l := len(p); l > 0 && (p[l-1] == '\'' || p[l-1] == 'h' || p[l-1] == 'H') {
+			p = p[:l-1]
+			h = HardenedKeyStart
+		}
+		i, err := strconv.ParseUint(p, 10, 31)
+		if err != nil || p[0] == '+' {
+			return nil, InvPath
+		}
+		res[j] = uint32(i) | h
+	}
+	return res, nil
+}
+
+// FormatPath returns the path p in the form "m/84'/0'/0'/0/5".
+func FormatPath(p []uint32) string {
+	var b strings.Builder
+	b.WriteString("m")
+	for _, i := range p {
+		b.WriteString("/")
+		b.WriteString(strconv.FormatUint(uint64(i&^HardenedKeyStart), 10))
+		if i >= HardenedKeyStart {
+			b.WriteString("'")
+		}
+	}
+	return b.String()
+}
+
+// Neuter returns the extended public key of e.
+func (e *ExtendedKey) Neuter() *ExtendedKey {
+	if !e.private {
+		return e
+	}
+	return &ExtendedKey{pub: e.pub, chainCode: e.chainCode, parentFP: e.parentFP, depth: e.depth, childNum: e.childNum, net: e.net}
+}
+
+// IsPrivate returns true if e is an extended private key.
+func (e *ExtendedKey) IsPrivate() bool {
+	return e.private
+}
+
+// PrKey returns the private key of e. Returns NotPrivate for public keys.
+func (e *ExtendedKey) PrKey() (*PrKey, error) {
+	if !e.private {
+		return nil, NotPrivate
+	}
+	k := e.key
+	return &k, nil
+}
+
+// PubKey returns the public key of e.
+func (e *ExtendedKey) PubKey() PublicKey {
+	return e.pub
+}
+
+// Fingerprint returns the fingerprint of e (the first 4 bytes of the hash160 of the compressed public key).
+func (e *ExtendedKey) Fingerprint() []byte {
+	return HashPubKey(e.pub.Compressed())[:4]
+}
+
+// ParentFingerprint returns the fingerprint of the parent key (zeros for the master key).
+func (e *ExtendedKey) ParentFingerprint() []byte {
+	return append([]byte(nil), e.parentFP...)
+}
+
+// Depth returns the depth of e (0 for the master key).
+func (e *ExtendedKey) Depth() byte {
+	return e.depth
+}
+
+// ChildNumber returns the index of e.
+func (e *ExtendedKey) ChildNumber() uint32 {
+	return e.childNum
+}
+
+// ChainCode returns the chain code of e.
+func (e *ExtendedKey) ChainCode() []byte {
+	return append([]byte(nil), e.chainCode...)
+}
+
+// Network returns the network of e. BTCMain is returned if the network is not set.
+func (e *ExtendedKey) Network() *Network {
+	if e.net == nil {
+		return BTCMain
+	}
+	return e.net
+}
+
+// SetNetwork sets the network of e and of the keys derived from it (used for serialization and addresses) and returns e.
+func (e *ExtendedKey) SetNetwork(n *Network) *ExtendedKey {
+	e.net = n
+	if e.private {
+		e.key.net = n
+	}
+	return e
+}
+
+// String returns the Base58Check serialization of e (xprv/xpub for Bitcoin mainnet).
+func (e *ExtendedKey) String() string {
+	n := e.Network()
+	v := n.HDPublic
+	if e.private {
+		v = n.HDPrivate
+	}
+	b := make([]byte, 0, 82)
+	b = binary.BigEndian.AppendUint32(b, v)
+	b = append(b, e.depth)
+	b = append(b, e.parentFP...)
+	b = binary.BigEndian.AppendUint32(b, e.childNum)
+	b = append(b, e.chainCode...)
+	if e.private {
+		b = append(b, 0x00)
+		b = append(b, bytesFull(&e.key.k)...)
+	} else {
+		b = append(b, e.pub.Compressed()...)
+	}
+	return string(Base58Encode(append(b, checksum(b)...)))
+}
+
+// ParseExtendedKey parses the Base58Check serialized extended key s.
+// The network is detected from the version bytes (see Networks). Testnet, signet and regtest keys
+// share the version bytes and are detected as BTCTest, use SetNetwork for signet or regtest.
+func ParseExtendedKey(s string) (*ExtendedKey, error) {
+	b, err := Base58Decode([]byte(strings.TrimSpace(s)))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != 82 {
+		return nil, InvExtKey
+	}
+	if string(checksum(b[:78])) != string(b[78:]) {
+		return nil, InvExtCSum
+	}
+	n, private := networkByHD(binary.BigEndian.Uint32(b))
+	if n == nil {
+		return nil, InvExtKey
+	}
+	e := &ExtendedKey{
+		depth:     b[4],
+		parentFP:  b[5:9],
+		childNum:  binary.BigEndian.Uint32(b[9:13]),
+		chainCode: b[13:45],
+		private:   private,
+		net:       n,
+	}
+	if e.depth == 0 && (binary.BigEndian.Uint32(e.parentFP) != 0 || e.childNum != 0) {
+		return nil, InvExtKey
+	}
+	kd := b[45:78]
+	if private {
+		if kd[0] != 0x00 {
+			return nil, InvExtKey
+		}
+		if _, err := e.key.SetBytes(kd[1:]); err != nil {
+			return nil, err
+		}
+		e.key.net = n
+		e.pub = e.key.PubK()
+		return e, nil
+	}
+	if kd[0] != 0x02 && kd[0] != 0x03 {
+		return nil, InvExtKey
+	}
+	if e.pub, err = ParsePubKey(kd); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
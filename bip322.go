@@ -0,0 +1,136 @@
+package cckat
+
+import (
+	"encoding/base64"
+	"strings"
+)
+
+// BIP322MessageHash returns the tagged hash of the message signed by SignBIP322 (BIP322).
+func BIP322MessageHash(msg string) []byte {
+	return taggedHash("BIP0322-signed-message", []byte(msg))
+}
+
+// bip322ToSpend returns the virtual to_spend transaction of msg for the output script pkScript.
+func bip322ToSpend(pkScript []byte, msg string) *Tx {
+	in := &TxIn{
+		PrevOut:   OutPoint{Index: 0xffffffff},
+		ScriptSig: append([]byte{0x00, 0x20}, BIP322MessageHash(msg)...),
+	}
+	return &Tx{TxIn: []*TxIn{in}, TxOut: []*TxOut{{PkScript: pkScript}}}
+}
+
+// bip322ToSign returns the virtual to_sign transaction spending toSpend with the witness w.
+func bip322ToSign(toSpend *Tx, w [][]byte) *Tx {
+	in := &TxIn{PrevOut: OutPoint{Hash: toSpend.TxHash()}, Witness: w}
+	return &Tx{TxIn: []*TxIn{in}, TxOut: []*TxOut{{PkScript: []byte{0x6a}}}}
+}
+
+// SignBIP322 returns the base64 encoded BIP322 signature of msg for the address of the type at (P2WPKH or P2TR,
+// key path spending). The simple signature (the witness stack) is returned if full is false, otherwise
+// the full signature (the serialized to_sign transaction). Returns UnsupAddrType for other types.
+// See https://github.com/bitcoin/bips/blob/master/bip-0322.mediawiki
+func (k *PrKey) SignBIP322(msg string, at AddressType, full bool) (string, error) {
+	if err := k.isSet(); err != nil {
+		return "", err
+	}
+	pub := PubKey(&k.k, false)
+	if at != P2WPKH && at != P2TR {
+		return "", UnsupAddrType
+	}
+	script, err := PkScript(pub, at)
+	if err != nil {
+		return "", err
+	}
+	toSpend := bip322ToSpend(script, msg)
+	toSign := bip322ToSign(toSpend, nil)
+	var w [][]byte
+	if at == P2WPKH {
+		h, err := toSign.SigHashWitnessV0(0, p2pkhScript(script[2:]), 0, SigHashAll)
+		if err != nil {
+			return "", err
+		}
+		sig, err := k.Sign(h)
+		if err != nil {
+			return "", err
+		}
+		w = [][]byte{append(sig.DER(), byte(SigHashAll)), pub}
+	} else {
+		h, err := toSign.SigHashTaproot(0, toSpend.TxOut, SigHashDefault, nil)
+		if err != nil {
+			return "", err
+		}
+		sig, err := k.SignTaproot(h, nil, nil)
+		if err != nil {
+			return "", err
+		}
+		w = [][]byte{sig}
+	}
+	toSign.TxIn[0].Witness = w
+	if full {
+		return base64.StdEncoding.EncodeToString(toSign.Serialize()), nil
+	}
+	return base64.StdEncoding.EncodeToString(appendWitness(nil, w)), nil
+}
+
+// VerifyBIP322 returns true if sig is a valid base64 encoded BIP322 simple or full signature of msg
+// for the P2WPKH or P2TR (key path) address addr of any network (see Networks).
+// The full signatures with additional inputs (proof of funds) are not supported.
+func VerifyBIP322(addr, msg, sig string) bool {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(sig))
+	if err != nil {
+		return false
+	}
+	var at AddressType
+	var prog []byte
+	for _, n := range Networks {
+		if at, prog, err = n.ParseAddress(addr); err == nil {
+			break
+		}
+	}
+	var script []byte
+	switch {
+	case err != nil:
+		return false
+	case at == P2WPKH:
+		script = witnessScript(0, prog)
+	case at == P2TR:
+		script = witnessScript(1, prog)
+	default:
+		return false
+	}
+	toSpend := bip322ToSpend(script, msg)
+	var toSign *Tx
+	r := &txReader{b: b}
+	if w := r.witness(); r.err == nil && len(r.b) == 0 {
+		toSign = bip322ToSign(toSpend, w)
+	} else if toSign, err = ParseTx(b); err != nil {
+		return false
+	}
+	if len(toSign.TxIn) != 1 || len(toSign.TxOut) != 1 || toSign.TxOut[0].Value != 0 ||
+		string(toSign.TxOut[0].PkScript) != "\x6a" || toSign.TxIn[0].PrevOut != (OutPoint{Hash: toSpend.TxHash()}) {
+		return false
+	}
+	w := toSign.TxIn[0].Witness
+	if at == P2WPKH {
+		if len(w) != 2 || len(w[0]) == 0 || len(w[1]) != 33 || string(HashPubKey(w[1])) != string(prog) {
+			return false
+		}
+		s, err := ParseDER(w[0][:len(w[0])-1])
+		if err != nil {
+			return false
+		}
+		h, err := toSign.SigHashWitnessV0(0, p2pkhScript(prog), 0, SigHashType(w[0][len(w[0])-1]))
+		return err == nil && VerifySignature(w[1], h, s)
+	}
+	if len(w) != 1 || (len(w[0]) != 64 && len(w[0]) != 65) {
+		return false
+	}
+	ht := SigHashDefault
+	if len(w[0]) == 65 {
+		if ht = SigHashType(w[0][64]); ht == SigHashDefault {
+			return false
+		}
+	}
+	h, err := toSign.SigHashTaproot(0, toSpend.TxOut, ht, nil)
+	return err == nil && VerifySchnorr(prog, h, w[0][:64])
+}
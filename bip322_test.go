@@ -0,0 +1,86 @@
+package cckat
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// BIP322 test vectors for the P2WPKH address of the key L3VFeEujGtevx9w18HD1fhRbCH67Az2dpCymeRE1SoPK6XQtaN2k.
+const (
+	bip322Addr = "bc1q9vza2e8x573nczrlzms0wvx3gsqjx7vavgkx0l"
+	bip322Key  = "L3VFeEujGtevx9w18HD1fhRbCH67Az2dpCymeRE1SoPK6XQtaN2k"
+)
+
+var bip322Tests = []struct {
+	msg, hash, toSpend, toSign, sig string
+}{
+	{"", "c90c269c4f8fcbe6880f72a721ddfbf1914268a794cbb21cfafee13770ae19f1",
+		"c5680aa69bb8d860bf82d4e9cd3504b55dde018de765a91bb566283c545a99a7",
+		"1e9654e951a5ba44c8604c4de6c67fd78a27e81dcadcfe1edf638ba3aaebaed6",
+		"AkcwRAIgM2gBAQqvZX15ZiysmKmQpDrG83avLIT492QBzLnQIxYCIBaTpOaD20qRlEylyxFSeEA2ba9YOixpX8z46TSDtS40ASECx/EgAxlkQpQ9hYjgGu6EBCPMVPwVIVJqO4XCsMvViHI="},
+	{"Hello World", "f0eb03b1a75ac6d9847f55c624a99169b5dccba2a31f5b23bea77ba270de0a7a",
+		"b79d196740ad5217771c1098fc4a4b51e0535c32236c71f1ea4d61a2d603352b",
+		"88737ae86f2077145f93cc4b153ae9a1cb8d56afa511988c149c5c8c9d93bddf",
+		"AkcwRAIgZRfIY3p7/DoVTty6YZbWS71bc5Vct9p9Fia83eRmw2QCICK/ENGfwLtptFluMGs2KsqoNSk89pO7F29zJLUx9a/sASECx/EgAxlkQpQ9hYjgGu6EBCPMVPwVIVJqO4XCsMvViHI="},
+}
+
+func TestBIP322Vectors(t *testing.T) {
+	k, err := new(PrKey).SetWIF(bip322Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := k.AddressT(P2WPKH); a != bip322Addr {
+		t.Fatalf("address %s, want %s", a, bip322Addr)
+	}
+	_, prog, _ := BTCMain.ParseAddress(bip322Addr)
+	for _, c := range bip322Tests {
+		if h := hex.EncodeToString(BIP322MessageHash(c.msg)); h != c.hash {
+			t.Errorf("%q: message hash %s, want %s", c.msg, h, c.hash)
+		}
+		toSpend := bip322ToSpend(witnessScript(0, prog), c.msg)
+		if id := toSpend.TxID(); id != c.toSpend {
+			t.Errorf("%q: to_spend %s, want %s", c.msg, id, c.toSpend)
+		}
+		if id := bip322ToSign(toSpend, nil).TxID(); id != c.toSign {
+			t.Errorf("%q: to_sign %s, want %s", c.msg, id, c.toSign)
+		}
+		if !VerifyBIP322(bip322Addr, c.msg, c.sig) {
+			t.Errorf("%q: not verified", c.msg)
+		}
+		// the vectors are signed by Bitcoin Core with low R grinding, so only the verification is compared
+		sig, err := k.SignBIP322(c.msg, P2WPKH, false)
+		if err != nil || !VerifyBIP322(bip322Addr, c.msg, sig) {
+			t.Errorf("%q: the simple signature is not verified: %v", c.msg, err)
+		}
+		full, err := k.SignBIP322(c.msg, P2WPKH, true)
+		if err != nil || !VerifyBIP322(bip322Addr, c.msg, full) {
+			t.Errorf("%q: the full signature is not verified: %v", c.msg, err)
+		}
+	}
+	if VerifyBIP322(bip322Addr, "Hello World", bip322Tests[0].sig) {
+		t.Error("the signature of the empty message is verified for \"Hello World\"")
+	}
+	if VerifyBIP322("bc1qxv6ey3qmyu7yw8r0gcmfs3xyrkh0ly3el6k4g6", "", bip322Tests[0].sig) {
+		t.Error("verified for another address")
+	}
+}
+
+func TestBIP322Taproot(t *testing.T) {
+	k, _ := new(PrKey).SetWIF(bip322Key)
+	addr := k.AddressT(P2TR)
+	for _, full := range []bool{false, true} {
+		sig, err := k.SignBIP322("Hello World", P2TR, full)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !VerifyBIP322(addr, "Hello World", sig) {
+			t.Errorf("full %t: not verified", full)
+		}
+		if VerifyBIP322(addr, "Hello World!", sig) {
+			t.Errorf("full %t: verified for another message", full)
+		}
+	}
+	if _, err := k.SignBIP322("", P2PKH, false); err != UnsupAddrType {
+		t.Errorf("P2PKH: %v", err)
+	}
+}
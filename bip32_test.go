@@ -0,0 +1,169 @@
+package cckat
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// BIP32 test vectors 1 - 4: the seed, the path and the serialized extended keys.
+var bip32Tests = []struct {
+	seed, path, pub, prv string
+}{
+	// vector 1
+	{"000102030405060708090a0b0c0d0e0f", "m",
+		"xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet8",
+		"xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi"},
+	{"000102030405060708090a0b0c0d0e0f", "m/0H",
+		"xpub68Gmy5EdvgibQVfPdqkBBCHxA5htiqg55crXYuXoQRKfDBFA1WEjWgP6LHhwBZeNK1VTsfTFUHCdrfp1bgwQ9xv5ski8PX9rL2dZXvgGDnw",
+		"xprv9uHRZZhk6KAJC1avXpDAp4MDc3sQKNxDiPvvkX8Br5ngLNv1TxvUxt4cV1rGL5hj6KCesnDYUhd7oWgT11eZG7XnxHrnYeSvkzY7d2bhkJ7"},
+	{"000102030405060708090a0b0c0d0e0f", "m/0H/1",
+		"xpub6ASuArnXKPbfEwhqN6e3mwBcDTgzisQN1wXN9BJcM47sSikHjJf3UFHKkNAWbWMiGj7Wf5uMash7SyYq527Hqck2AxYysAA7xmALppuCkwQ",
+		"xprv9wTYmMFdV23N2TdNG573QoEsfRrWKQgWeibmLntzniatZvR9BmLnvSxqu53Kw1UmYPxLgboyZQaXwTCg8MSY3H2EU4pWcQDnRnrVA1xe8fs"},
+	{"000102030405060708090a0b0c0d0e0f", "m/0H/1/2H",
+		"xpub6D4BDPcP2GT577Vvch3R8wDkScZWzQzMMUm3PWbmWvVJrZwQY4VUNgqFJPMM3No2dFDFGTsxxpG5uJh7n7epu4trkrX7x7DogT5Uv6fcLW5",
+		"xprv9z4pot5VBttmtdRTWfWQmoH1taj2axGVzFqSb8C9xaxKymcFzXBDptWmT7FwuEzG3ryjH4ktypQSAewRiNMjANTtpgP4mLTj34bhnZX7UiM"},
+	{"000102030405060708090a0b0c0d0e0f", "m/0H/1/2H/2",
+		"xpub6FHa3pjLCk84BayeJxFW2SP4XRrFd1JYnxeLeU8EqN3vDfZmbqBqaGJAyiLjTAwm6ZLRQUMv1ZACTj37sR62cfN7fe5JnJ7dh8zL4fiyLHV",
+		"xprvA2JDeKCSNNZky6uBCviVfJSKyQ1mDYahRjijr5idH2WwLsEd4Hsb2Tyh8RfQMuPh7f7RtyzTtdrbdqqsunu5Mm3wDvUAKRHSC34sJ7in334"},
+	{"000102030405060708090a0b0c0d0e0f", "m/0H/1/2H/2/1000000000",
+		"xpub6H1LXWLaKsWFhvm6RVpEL9P4KfRZSW7abD2ttkWP3SSQvnyA8FSVqNTEcYFgJS2UaFcxupHiYkro49S8yGasTvXEYBVPamhGW6cFJodrTHy",
+		"xprvA41z7zogVVwxVSgdKUHDy1SKmdb533PjDz7J6N6mV6uS3ze1ai8FHa8kmHScGpWmj4WggLyQjgPie1rFSruoUihUZREPSL39UNdE3BBDu76"},
+	// vector 2
+	{"fffcf9f6f3f0edeae7e4e1dedbd8d5d2cfccc9c6c3c0bdbab7b4b1aeaba8a5a29f9c999693908d8a8784817e7b7875726f6c696663605d5a5754514e4b484542", "m",
+		"xpub661MyMwAqRbcFW31YEwpkMuc5THy2PSt5bDMsktWQcFF8syAmRUapSCGu8ED9W6oDMSgv6Zz8idoc4a6mr8BDzTJY47LJhkJ8UB7WEGuduB",
+		"xprv9s21ZrQH143K31xYSDQpPDxsXRTUcvj2iNHm5NUtrGiGG5e2DtALGdso3pGz6ssrdK4PFmM8NSpSBHNqPqm55Qn3LqFtT2emdEXVYsCzC2U"},
+	{"fffcf9f6f3f0edeae7e4e1dedbd8d5d2cfccc9c6c3c0bdbab7b4b1aeaba8a5a29f9c999693908d8a8784817e7b7875726f6c696663605d5a5754514e4b484542", "m/0",
+		"xpub69H7F5d8KSRgmmdJg2KhpAK8SR3DjMwAdkxj3ZuxV27CprR9LgpeyGmXUbC6wb7ERfvrnKZjXoUmmDznezpbZb7ap6r1D3tgFxHmwMkQTPH",
+		"xprv9vHkqa6EV4sPZHYqZznhT2NPtPCjKuDKGY38FBWLvgaDx45zo9WQRUT3dKYnjwih2yJD9mkrocEZXo1ex8G81dwSM1fwqWpWkeS3v86pgKt"},
+	{"fffcf9f6f3f0edeae7e4e1dedbd8d5d2cfccc9c6c3c0bdbab7b4b1aeaba8a5a29f9c999693908d8a8784817e7b7875726f6c696663605d5a5754514e4b484542", "m/0/2147483647H",
+		"xpub6ASAVgeehLbnwdqV6UKMHVzgqAG8Gr6riv3Fxxpj8ksbH9ebxaEyBLZ85ySDhKiLDBrQSARLq1uNRts8RuJiHjaDMBU4Zn9h8LZNnBC5y4a",
+		"xprv9wSp6B7kry3Vj9m1zSnLvN3xH8RdsPP1Mh7fAaR7aRLcQMKTR2vidYEeEg2mUCTAwCd6vnxVrcjfy2kRgVsFawNzmjuHc2YmYRmagcEPdU9"},
+	{"fffcf9f6f3f0edeae7e4e1dedbd8d5d2cfccc9c6c3c0bdbab7b4b1aeaba8a5a29f9c999693908d8a8784817e7b7875726f6c696663605d5a5754514e4b484542", "m/0/2147483647H/1",
+		"xpub6DF8uhdarytz3FWdA8TvFSvvAh8dP3283MY7p2V4SeE2wyWmG5mg5EwVvmdMVCQcoNJxGoWaU9DCWh89LojfZ537wTfunKau47EL2dhHKon",
+		"xprv9zFnWC6h2cLgpmSA46vutJzBcfJ8yaJGg8cX1e5StJh45BBciYTRXSd25UEPVuesF9yog62tGAQtHjXajPPdbRCHuWS6T8XA2ECKADdw4Ef"},
+	{"fffcf9f6f3f0edeae7e4e1dedbd8d5d2cfccc9c6c3c0bdbab7b4b1aeaba8a5a29f9c999693908d8a8784817e7b7875726f6c696663605d5a5754514e4b484542", "m/0/2147483647H/1/2147483646H",
+		"xpub6ERApfZwUNrhLCkDtcHTcxd75RbzS1ed54G1LkBUHQVHQKqhMkhgbmJbZRkrgZw4koxb5JaHWkY4ALHY2grBGRjaDMzQLcgJvLJuZZvRcEL",
+		"xprvA1RpRA33e1JQ7ifknakTFpgNXPmW2YvmhqLQYMmrj4xJXXWYpDPS3xz7iAxn8L39njGVyuoseXzU6rcxFLJ8HFsTjSyQbLYnMpCqE2VbFWc"},
+	{"fffcf9f6f3f0edeae7e4e1dedbd8d5d2cfccc9c6c3c0bdbab7b4b1aeaba8a5a29f9c999693908d8a8784817e7b7875726f6c696663605d5a5754514e4b484542", "m/0/2147483647H/1/2147483646H/2",
+		"xpub6FnCn6nSzZAw5Tw7cgR9bi15UV96gLZhjDstkXXxvCLsUXBGXPdSnLFbdpq8p9HmGsApME5hQTZ3emM2rnY5agb9rXpVGyy3bdW6EEgAtqt",
+		"xprvA2nrNbFZABcdryreWet9Ea4LvTJcGsqrMzxHx98MMrotbir7yrKCEXw7nadnHM8Dq38EGfSh6dqA9QWTyefMLEcBYJUuekgW4BYPJcr9E7j"},
+	// vector 3: the private key with leading zeros
+	{"4b381541583be4423346c643850da4b320e46a87ae3d2a4e6da11eba819cd4acba45d239319ac14f863b8d5ab5a0d0c64d2e8a1e7d1457df2e5a3c51c73235be", "m",
+		"xpub661MyMwAqRbcEZVB4dScxMAdx6d4nFc9nvyvH3v4gJL378CSRZiYmhRoP7mBy6gSPSCYk6SzXPTf3ND1cZAceL7SfJ1Z3GC8vBgp2epUt13",
+		"xprv9s21ZrQH143K25QhxbucbDDuQ4naNntJRi4KUfWT7xo4EKsHt2QJDu7KXp1A3u7Bi1j8ph3EGsZ9Xvz9dGuVrtHHs7pXeTzjuxBrCmmhgC6"},
+	{"4b381541583be4423346c643850da4b320e46a87ae3d2a4e6da11eba819cd4acba45d239319ac14f863b8d5ab5a0d0c64d2e8a1e7d1457df2e5a3c51c73235be", "m/0H",
+		"xpub68NZiKmJWnxxS6aaHmn81bvJeTESw724CRDs6HbuccFQN9Ku14VQrADWgqbhhTHBaohPX4CjNLf9fq9MYo6oDaPPLPxSb7gwQN3ih19Zm4Y",
+		"xprv9uPDJpEQgRQfDcW7BkF7eTya6RPxXeJCqCJGHuCJ4GiRVLzkTXBAJMu2qaMWPrS7AANYqdq6vcBcBUdJCVVFceUvJFjaPdGZ2y9WACViL4L"},
+	// vector 4: the private key with leading zeros in the hardened derivation
+	{"3ddd5602285899a946114506157c7997e5444528f3003f6134712147db19b678", "m",
+		"xpub661MyMwAqRbcGczjuMoRm6dXaLDEhW1u34gKenbeYqAix21mdUKJyuyu5F1rzYGVxyL6tmgBUAEPrEz92mBXjByMRiJdba9wpnN37RLLAXa",
+		"xprv9s21ZrQH143K48vGoLGRPxgo2JNkJ3J3fqkirQC2zVdk5Dgd5w14S7fRDyHH4dWNHUgkvsvNDCkvAwcSHNAQwhwgNMgZhLtQC63zxwhQmRv"},
+	{"3ddd5602285899a946114506157c7997e5444528f3003f6134712147db19b678", "m/0H",
+		"xpub69AUMk3qDBi3uW1sXgjCmVjJ2G6WQoYSnNHyzkmdCHEhSZ4tBok37xfFEqHd2AddP56Tqp4o56AePAgCjYdvpW2PU2jbUPFKsav5ut6Ch1m",
+		"xprv9vB7xEWwNp9kh1wQRfCCQMnZUEG21LpbR9NPCNN1dwhiZkjjeGRnaALmPXCX7SgjFTiCTT6bXes17boXtjq3xLpcDjzEuGLQBM5ohqkao9G"},
+	{"3ddd5602285899a946114506157c7997e5444528f3003f6134712147db19b678", "m/0H/1H",
+		"xpub6BJA1jSqiukeaesWfxe6sNK9CCGaujFFSJLomWHprUL9DePQ4JDkM5d88n49sMGJxrhpjazuXYWdMf17C9T5XnxkopaeS7jGk1GyyVziaMt",
+		"xprv9xJocDuwtYCMNAo3Zw76WENQeAS6WGXQ55RCy7tDJ8oALr4FWkuVoHJeHVAcAqiZLE7Je3vZJHxspZdFHfnBEjHqU5hG1Jaj32dVoS6XLT1"},
+}
+
+func TestBIP32Vectors(t *testing.T) {
+	for _, c := range bip32Tests {
+		m, err := NewMasterKey(mustHex(t, c.seed), BTCMain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e, err := m.Derive(c.path)
+		if err != nil {
+			t.Fatalf("%s: %v", c.path, err)
+		}
+		if e.String() != c.prv {
+			t.Errorf("%s: got %s, want %s", c.path, e, c.prv)
+		}
+		if e.Neuter().String() != c.pub {
+			t.Errorf("%s: got %s, want %s", c.path, e.Neuter(), c.pub)
+		}
+		for _, s := range []string{c.prv, c.pub} {
+			p, err := ParseExtendedKey(s)
+			if err != nil {
+				t.Fatalf("%s: %v", s, err)
+			}
+			if p.String() != s {
+				t.Errorf("round trip: got %s, want %s", p, s)
+			}
+		}
+	}
+}
+
+func TestBIP32NilNetwork(t *testing.T) {
+	m, err := NewMasterKey(mustHex(t, bip32Tests[0].seed), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Network() != BTCMain || m.String() != bip32Tests[0].prv {
+		t.Errorf("got %s, %s", m.Network().Name, m)
+	}
+	if m.SetNetwork(nil).Neuter().String() != bip32Tests[0].pub {
+		t.Errorf("SetNetwork(nil): got %s", m.Neuter())
+	}
+}
+
+// TestBIP32PublicDerivation checks that CKDpub of the parent matches CKDpriv for the non-hardened children.
+func TestBIP32PublicDerivation(t *testing.T) {
+	for i, c := range bip32Tests {
+		p, _ := ParsePath(c.path)
+		if len(p) == 0 || p[len(p)-1] >= HardenedKeyStart {
+			continue
+		}
+		parent := bip32Tests[i-1]
+		e, err := ParseExtendedKey(parent.pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e, err = e.Child(p[len(p)-1]); err != nil {
+			t.Fatal(err)
+		}
+		if e.String() != c.pub {
+			t.Errorf("%s: got %s, want %s", c.path, e, c.pub)
+		}
+	}
+	e, _ := ParseExtendedKey(bip32Tests[0].pub)
+	if _, err := e.Child(HardenedKeyStart); err != HardenedPub {
+		t.Errorf("hardened child of a public key: %v", err)
+	}
+}
+
+// TestBIP32Invalid checks the invalid keys of BIP32 test vector 5, made from the vector 1 master key.
+func TestBIP32Invalid(t *testing.T) {
+	valid, err := Base58Decode([]byte(bip32Tests[0].prv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, _ := Base58Decode([]byte(bip32Tests[0].pub))
+	mod := func(b []byte, f func(b []byte)) string {
+		b = append([]byte(nil), b[:78]...)
+		f(b)
+		return string(Base58Encode(append(b, checksum(b)...)))
+	}
+	for _, c := range []struct {
+		name, key string
+		err       error
+	}{
+		{"pubkey version with a private key", mod(valid, func(b []byte) { copy(b, pub[:4]) }), InvExtKey},
+		{"private version with a public key", mod(pub, func(b []byte) { copy(b, valid[:4]) }), InvExtKey},
+		{"unknown version", mod(valid, func(b []byte) { binary.BigEndian.PutUint32(b, 0xdeadbeef) }), InvExtKey},
+		{"public key prefix 04", mod(pub, func(b []byte) { b[45] = 0x04 }), InvExtKey},
+		{"private key prefix 04", mod(valid, func(b []byte) { b[45] = 0x04 }), InvExtKey},
+		{"private key prefix 01", mod(valid, func(b []byte) { b[45] = 0x01 }), InvExtKey},
+		{"zero depth, non-zero parent fingerprint", mod(valid, func(b []byte) { b[5] = 0x01 }), InvExtKey},
+		{"zero depth, non-zero index", mod(pub, func(b []byte) { b[12] = 0x01 }), InvExtKey},
+		{"private key 0", mod(valid, func(b []byte) { copy(b[46:], make([]byte, 32)) }), PKeyOutOfR},
+		{"private key n", mod(valid, func(b []byte) { copy(b[46:], bytesFull(secp256k1.N)) }), PKeyOutOfR},
+		{"public key not on the curve", mod(pub, func(b []byte) { copy(b[46:], mustHex(t, xNotOnCurve)) }), NoSuchPoin},
+		{"checksum", bip32Tests[0].prv[:110] + "j", InvExtCSum},
+		{"length", string(Base58Encode(append(valid[:77:77], checksum(valid[:77])...))), InvExtKey},
+	} {
+		if _, err := ParseExtendedKey(c.key); err != c.err {
+			t.Errorf("%s: %v, want %v", c.name, err, c.err)
+		}
+	}
+}
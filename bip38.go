@@ -3,8 +3,8 @@ package cckat
 import (
 	"bytes"
 	"crypto/aes"
-	"fmt"
 	"golang.org/x/crypto/scrypt"
+	"golang.org/x/text/unicode/norm"
 )
 
 // Encrypt returns the encrypted private key (BIP038) for the private key k. (no EC multiply).
@@ -15,7 +15,7 @@ func Encrypt(k PrKey, passphrase string) string {
 		k.SetAddressType(P2PKH)
 	}
 	ah := checksum([]byte(k.Address()))
-	dh, _ := scrypt.Key([]byte(passphrase), ah, 16384, 8, 8, 64)
+	dh, _ := scrypt.Key([]byte(norm.NFC.String(passphrase)), ah, 16384, 8, 8, 64)
 	var flag byte = 0xE0
 	if k.uncomp {
 		flag = 0xC0
@@ -33,21 +33,35 @@ func Encrypt(k PrKey, passphrase string) string {
 
 }
 
-// Decrypt BIP38 string which does not have the ECMultiply flag set
+// Decrypt BIP38 string (0x0142 prefix, "6P" + "R"/"Y", or EC multiply mode, 0x0143 prefix, "6P" + "f"/"n").
 func Decrypt(b, password string) (*PrKey, error) {
-	bk, _ := Base58Decode([]byte(b))
+	return decryptNet(b, password, BTCMain)
+}
+
+// decryptNet decrypts BIP38 string of the private key of the network n.
+func decryptNet(b, password string, n *Network) (*PrKey, error) {
+	bk, err := Base58Decode([]byte(b))
+	if err != nil {
+		return nil, err
+	}
+	if len(bk) != 43 || bk[0] != 0x01 || (bk[1] != 0x42 && bk[1] != 0x43) {
+		return nil, BIP38InvKey
+	}
 	flag := bk[2]
 	h := bk[3:7]
 	data := bk[7:]
 	if !bytes.Equal(checksum(bk[:len(bk)-4]), data[len(data)-4:]) {
 		return nil, BIP38InvCSum
 	}
-	dh, err := scrypt.Key([]byte(password), h, 16384, 8, 8, 64)
+	if bk[1] == 0x43 {
+		return decryptEC(bk, password, n)
+	}
+	dh, err := scrypt.Key([]byte(norm.NFC.String(password)), h, 16384, 8, 8, 64)
 	if err != nil {
 		return nil, err
 	}
 	p := decrypt(data, dh[:32], dh[32:])
-	pk, err := new(PrKey).SetBytes(p)
+	pk, err := new(PrKey).SetNetwork(n).SetBytes(p)
 	if err != nil {
 		return nil, err
 	}
@@ -57,7 +71,6 @@ func Decrypt(b, password string) (*PrKey, error) {
 	} else {
 		pk.SetAddressType(P2PKH)
 	}
-	fmt.Println()
 	if !bytes.Equal(h, checksum([]byte(pk.Address()))) {
 		return nil, BIP38PassErr
 	}
@@ -0,0 +1,88 @@
+package cckat
+
+import (
+	"bytes"
+	"testing"
+)
+
+// BIP38 EC multiply test vectors (the compressed flag is not set).
+var bip38ECTests = []struct {
+	enc, pass, addr, wif, cfrm string
+}{
+	// no lot and sequence numbers
+	{"6PfQu77ygVyJLZjfvMLyhLMQbYnu5uguoJJ4kMCLqWwPEdfpwANVS76gTX", "TestingOneTwoThree",
+		"1PE6TQi6HTVNz5DLwB1LcpMBALubfuN2z2", "5K4caxezwjGCGfnoPTZ8tMcJBLB7Jvyjv4xxeacadhq8nLisLR2", ""},
+	{"6PfLGnQs6VZnrNpmVKfjotbnQuaJK4KZoPFrAjx1JMJUa1Ft8gnf5WxfKd", "Satoshi",
+		"1CqzrtZC6mXSAhoxtFwVjz8LtwLJjDYU3V", "5KJ51SgxWaAYR13zd9ReMhJpwrcX47xTJh2D3fGPG9CM8vkv5sH", ""},
+	// lot and sequence numbers
+	{"6PgNBNNzDkKdhkT6uJntUXwwzQV8Rr2tZcbkDcuC9DZRsS6AtHts4Ypo1j", "MOLON LABE",
+		"1Jscj8ALrYu2y9TD8NrpvDBugPedmbj4Yh", "5JLdxTtcTHcfYcmJsNVy1v2PMDx432JPoYcBTVVRHpPaxUrdtf8",
+		"cfrm38V8aXBn7JWA1ESmFMUn6erxeBGZGAxJPY4e36S9QWkzZKtaVqLNMgnifETYw7BPwWC9aPD"},
+	{"6PgGWtx25kUg8QWvwuJAgorN6k9FbE25rv5dMRwu5SKMnfpfVe5mar2ngH", "ΜΟΛΩΝ ΛΑΒΕ",
+		"1Lurmih3KruL4xDB5FmHof38yawNtP9oGf", "5KMKKuUmAkiNbA3DazMQiLfDq47qs8MAEThm4yL8R2PhV1ov33D",
+		"cfrm38V8G4qq2ywYEFfWLD5Cc6msj9UwsG2Mj4Z6QdGJAFQpdatZLavkgRd1i4iBMdRngDqDs51"},
+}
+
+func TestBIP38ECDecrypt(t *testing.T) {
+	for _, c := range bip38ECTests {
+		k, err := Decrypt(c.enc, c.pass)
+		if err != nil {
+			t.Fatalf("%s: %v", c.enc, err)
+		}
+		if k.WIF() != c.wif || k.Address() != c.addr {
+			t.Errorf("%s: got %s %s, want %s %s", c.enc, k.WIF(), k.Address(), c.wif, c.addr)
+		}
+		if _, err := Decrypt(c.enc, c.pass+"x"); err != BIP38PassErr {
+			t.Errorf("%s: wrong passphrase: %v", c.enc, err)
+		}
+		if c.cfrm == "" {
+			continue
+		}
+		addr, err := VerifyConfirmation(c.cfrm, c.pass, BTCMain)
+		if err != nil || addr != c.addr {
+			t.Errorf("%s: got %s, %v, want %s", c.cfrm, addr, err, c.addr)
+		}
+		if _, err := VerifyConfirmation(c.cfrm, c.pass+"x", BTCMain); err != BIP38PassErr {
+			t.Errorf("%s: wrong passphrase: %v", c.cfrm, err)
+		}
+	}
+}
+
+func TestBIP38ECEncrypt(t *testing.T) {
+	const pass = "TestingOneTwoThree"
+	rand := bytes.NewReader(bytes.Repeat([]byte{0x5a}, 128))
+	code, err := IntermediateCodeLotSeq(rand, pass, 263183, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range []*Network{BTCMain, BTCTest} {
+		for _, comp := range []bool{false, true} {
+			enc, cfrm, addr, err := EncryptFromCode(rand, code, comp, n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if a, err := VerifyConfirmation(cfrm, pass, n); err != nil || a != addr {
+				t.Errorf("%s: got %s, %v, want %s", cfrm, a, err, addr)
+			}
+			k, err := new(PrKey).SetNetwork(n).SetBIP38(enc, pass)
+			if err != nil {
+				t.Fatalf("%s: %v", enc, err)
+			}
+			at := P2PKHUncomp
+			if comp {
+				at = P2PKH
+			}
+			if k.IsUncomp() == comp || k.AddressT(at) != addr {
+				t.Errorf("%s: the decrypted key does not match %s", enc, addr)
+			}
+			if n != BTCMain {
+				if _, err := Decrypt(enc, pass); err != BIP38PassErr {
+					t.Errorf("%s: decrypted for another network: %v", enc, err)
+				}
+			}
+		}
+	}
+	if _, err := IntermediateCodeLotSeq(rand, pass, 1<<20, 0); err != BIP38InvLot {
+		t.Errorf("lot 1048576: %v", err)
+	}
+}
@@ -0,0 +1,271 @@
+package cckat
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/big"
+
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	BIP38InvCode = errors.New("invalid BIP38 intermediate code")
+	BIP38InvConf = errors.New("invalid BIP38 confirmation code")
+	BIP38InvLot  = errors.New("BIP38 lot must be less than 1048576 and sequence less than 4096")
+)
+
+// The magic bytes of the intermediate codes without and with lot and sequence numbers
+var (
+	icMagic       = []byte{0x2C, 0xE9, 0xB3, 0xE1, 0xFF, 0x39, 0xE2, 0x51}
+	icMagicLotSeq = []byte{0x2C, 0xE9, 0xB3, 0xE1, 0xFF, 0x39, 0xE2, 0x53}
+	cfrmMagic     = []byte{0x64, 0x3B, 0xF6, 0xA8, 0x9A}
+)
+
+// IntermediateCode returns the BIP38 intermediate code ("passphrase...") for the passphrase (EC multiply mode
+// without lot and sequence numbers). The owner salt is read from rand.
+// The code can be given to a third party to generate encrypted keys with EncryptFromCode.
+func IntermediateCode(rand io.Reader, passphrase string) (string, error) {
+	salt := make([]byte, 8)
+	if _, err := io.ReadFull(rand, salt); err != nil {
+		return "", err
+	}
+	pf, err := passFactor(passphrase, salt, false)
+	if err != nil {
+		return "", err
+	}
+	return intermediateCode(icMagic, salt, pf), nil
+}
+
+// IntermediateCodeLotSeq returns the BIP38 intermediate code for the passphrase with the lot (< 1048576)
+// and sequence (< 4096) numbers. 4 bytes of the owner salt are read from rand.
+func IntermediateCodeLotSeq(rand io.Reader, passphrase string, lot, seq uint32) (string, error) {
+	if lot >= 1<<20 || seq >= 1<<12 {
+		return "", BIP38InvLot
+	}
+	oe := make([]byte, 4, 8)
+	if _, err := io.ReadFull(rand, oe); err != nil {
+		return "", err
+	}
+	oe = binary.BigEndian.AppendUint32(oe, lot<<12|seq)
+	pf, err := passFactor(passphrase, oe, true)
+	if err != nil {
+		return "", err
+	}
+	return intermediateCode(icMagicLotSeq, oe, pf), nil
+}
+
+func intermediateCode(magic, oe []byte, pf *big.Int) string {
+	buf := make([]byte, 0, 53)
+	buf = append(buf, magic...)
+	buf = append(buf, oe...)
+	buf = append(buf, PubKey(pf, false)...)
+	return string(Base58Encode(append(buf, checksum(buf)...)))
+}
+
+// passFactor returns the passfactor for the passphrase and the owner entropy oe.
+func passFactor(passphrase string, oe []byte, lotSeq bool) (*big.Int, error) {
+	salt := oe
+	if lotSeq {
+		salt = oe[:4]
+	}
+	pf, err := scrypt.Key([]byte(norm.NFC.String(passphrase)), salt, 16384, 8, 8, 32)
+	if err != nil {
+		return nil, err
+	}
+	if lotSeq {
+		pf = dsha256(append(pf, oe...))
+	}
+	k := new(big.Int).SetBytes(pf)
+	if k.Sign() == 0 || k.Cmp(secp256k1.N) >= 0 {
+		return nil, PKeyOutOfR
+	}
+	return k, nil
+}
+
+// EncryptFromCode generates a new private key from the intermediate code using the random seed read from rand
+// and returns the BIP38 encrypted private key, the confirmation code and the P2PKH address of the network n
+// (compressed pubkey if comp == true). The address hash in the encrypted key depends on the network,
+// the key must be decrypted for the same network (see PrKey.SetBIP38). The private key is not known to the caller,
+// it can be decrypted only with the passphrase of the intermediate code.
+func EncryptFromCode(rand io.Reader, code string, comp bool, n *Network) (enc, cfrm, addr string, err error) {
+	d, err := Base58Decode([]byte(code))
+	if err != nil {
+		return
+	}
+	if len(d) != 53 || !bytes.Equal(checksum(d[:49]), d[49:]) {
+		return "", "", "", BIP38InvCode
+	}
+	var flag byte
+	switch {
+	case bytes.Equal(d[:8], icMagicLotSeq):
+		flag = 0x04
+	case !bytes.Equal(d[:8], icMagic):
+		return "", "", "", BIP38InvCode
+	}
+	if comp {
+		flag |= 0x20
+	}
+	oe := d[8:16]
+	ppx, ppy, err := PointFromXc(d[17:49], d[16] == 0x02)
+	if err != nil || (d[16] != 0x02 && d[16] != 0x03) {
+		return "", "", "", BIP38InvCode
+	}
+	seedb := make([]byte, 24)
+	if _, err = io.ReadFull(rand, seedb); err != nil {
+		return
+	}
+	fb := new(big.Int).SetBytes(dsha256(seedb))
+	if fb.Sign() == 0 || fb.Cmp(secp256k1.N) >= 0 {
+		return "", "", "", PKeyOutOfR
+	}
+	nx, ny := secp256k1.ScalarMult(ppx, ppy, fb.Bytes())
+	np, _ := NewPublicKey(nx, ny)
+	if addr, err = getAddressP2PKH(np, comp, n); err != nil {
+		return
+	}
+	ah := checksum([]byte(addr))
+	dh, err := scrypt.Key(d[16:49], append(append([]byte(nil), ah...), oe...), 1024, 1, 1, 64)
+	if err != nil {
+		return
+	}
+	ep1 := aesEncrypt(xorBytes(seedb[:16], dh[:16]), dh[32:])
+	ep2 := aesEncrypt(xorBytes(append(append([]byte(nil), ep1[8:]...), seedb[16:]...), dh[16:32]), dh[32:])
+	buf := make([]byte, 0, 43)
+	buf = append(buf, 0x01, 0x43, flag)
+	buf = append(buf, ah...)
+	buf = append(buf, oe...)
+	buf = append(buf, ep1[:8]...)
+	buf = append(buf, ep2...)
+	enc = string(Base58Encode(append(buf, checksum(buf)...)))
+
+	pb := PubKey(fb, false)
+	epb := make([]byte, 1, 33)
+	epb[0] = pb[0] ^ dh[63]&0x01
+	epb = append(epb, aesEncrypt(xorBytes(pb[1:17], dh[:16]), dh[32:])...)
+	epb = append(epb, aesEncrypt(xorBytes(pb[17:], dh[16:32]), dh[32:])...)
+	buf = make([]byte, 0, 55)
+	buf = append(buf, cfrmMagic...)
+	buf = append(buf, flag)
+	buf = append(buf, ah...)
+	buf = append(buf, oe...)
+	buf = append(buf, epb...)
+	cfrm = string(Base58Encode(append(buf, checksum(buf)...)))
+	return enc, cfrm, addr, nil
+}
+
+// VerifyConfirmation checks the confirmation code cfrm with the passphrase
+// and returns the P2PKH address of the network n of the encrypted private key generated with EncryptFromCode.
+// Returns BIP38PassErr if the passphrase is wrong or the key was generated for another network.
+func VerifyConfirmation(cfrm, passphrase string, n *Network) (string, error) {
+	d, err := Base58Decode([]byte(cfrm))
+	if err != nil {
+		return "", err
+	}
+	if len(d) != 55 || !bytes.Equal(d[:5], cfrmMagic) || !bytes.Equal(checksum(d[:51]), d[51:]) {
+		return "", BIP38InvConf
+	}
+	flag := d[5]
+	ah := d[6:10]
+	oe := d[10:18]
+	epb := d[18:51]
+	pf, err := passFactor(passphrase, oe, flag&0x04 != 0)
+	if err != nil {
+		return "", err
+	}
+	dh, err := scrypt.Key(PubKey(pf, false), d[6:18], 1024, 1, 1, 64)
+	if err != nil {
+		return "", err
+	}
+	pb := make([]byte, 1, 33)
+	pb[0] = epb[0] ^ dh[63]&0x01
+	pb = append(pb, xorBytes(aesDecrypt(epb[1:17], dh[32:]), dh[:16])...)
+	pb = append(pb, xorBytes(aesDecrypt(epb[17:], dh[32:]), dh[16:32])...)
+	p, err := ParsePubKey(pb)
+	if err != nil || (pb[0] != 0x02 && pb[0] != 0x03) {
+		return "", BIP38PassErr
+	}
+	px, py := p.Point()
+	nx, ny := secp256k1.ScalarMult(px, py, pf.Bytes())
+	np, _ := NewPublicKey(nx, ny)
+	addr, err := getAddressP2PKH(np, flag&0x20 != 0, n)
+	if err != nil {
+		return "", err
+	}
+	if !bytes.Equal(checksum([]byte(addr)), ah) {
+		return "", BIP38PassErr
+	}
+	return addr, nil
+}
+
+// decryptEC decrypts the BIP38 key bk (decoded, 0x0143 prefix) encrypted in EC multiply mode.
+func decryptEC(bk []byte, passphrase string, n *Network) (*PrKey, error) {
+	flag := bk[2]
+	ah := bk[3:7]
+	oe := bk[7:15]
+	pf, err := passFactor(passphrase, oe, flag&0x04 != 0)
+	if err != nil {
+		return nil, err
+	}
+	dh, err := scrypt.Key(PubKey(pf, false), bk[3:15], 1024, 1, 1, 64)
+	if err != nil {
+		return nil, err
+	}
+	p2 := xorBytes(aesDecrypt(bk[23:39], dh[32:]), dh[16:32])
+	p1 := xorBytes(aesDecrypt(append(append([]byte(nil), bk[15:23]...), p2[:8]...), dh[32:]), dh[:16])
+	fb := new(big.Int).SetBytes(dsha256(append(p1, p2[8:]...)))
+	k := fb.Mul(fb, pf)
+	k.Mod(k, secp256k1.N)
+	pk, err := new(PrKey).SetNetwork(n).Set(*k)
+	if err != nil {
+		return nil, BIP38PassErr
+	}
+	pk.uncomp = flag&0x20 == 0
+	addr, err := getAddressP2PKH(pk.PubK(), !pk.uncomp, n)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(checksum([]byte(addr)), ah) {
+		return nil, BIP38PassErr
+	}
+	if pk.uncomp {
+		pk.a = P2PKHUncomp
+	}
+	return pk, nil
+}
+
+// aesEncrypt returns AES256 encryption of the 16 bytes block b with the key.
+func aesEncrypt(b, key []byte) []byte {
+	c, _ := aes.NewCipher(key)
+	dst := make([]byte, 16)
+	c.Encrypt(dst, b)
+	return dst
+}
+
+// aesDecrypt returns AES256 decryption of the 16 bytes block b with the key.
+func aesDecrypt(b, key []byte) []byte {
+	c, _ := aes.NewCipher(key)
+	dst := make([]byte, 16)
+	c.Decrypt(dst, b)
+	return dst
+}
+
+// xorBytes returns a xor b (len(a) bytes).
+func xorBytes(a, b []byte) []byte {
+	r := make([]byte, len(a))
+	for i := range r {
+		r[i] = a[i] ^ b[i]
+	}
+	return r
+}
+
+// dsha256 returns SHA256(SHA256(b)).
+func dsha256(b []byte) []byte {
+	h := sha256.Sum256(b)
+	h = sha256.Sum256(h[:])
+	return h[:]
+}
@@ -0,0 +1,143 @@
+package cckat
+
+import (
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"crypto/sha512"
+	_ "embed"
+	"errors"
+	"io"
+	"math/big"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	InvEntropyLen  = errors.New("entropy length must be 128, 160, 192, 224 or 256 bits")
+	InvMnemonicLen = errors.New("mnemonic must consist of 12, 15, 18, 21 or 24 words")
+	InvMnemonicW   = errors.New("word is not in the wordlist")
+	InvMnemonicCS  = errors.New("invalid mnemonic checksum")
+	InvWordlist    = errors.New("wordlist must consist of 2048 unique words")
+)
+
+//go:embed wordlists/english.txt
+var englishTxt string
+
+// EnglishWordlist is the BIP39 English wordlist. It is used if the wordlist is nil.
+var EnglishWordlist, _ = NewWordlist(strings.Fields(englishTxt), " ")
+
+// Wordlist is a BIP39 wordlist.
+// See https://github.com/bitcoin/bips/blob/master/bip-0039/bip-0039-wordlists.md
+type Wordlist struct {
+	words []string
+	index map[string]int
+	sep   string
+}
+
+// NewWordlist returns the wordlist of 2048 words. sep is the separator of the words in the mnemonic
+// (" " if sep == "", the ideographic space "　" is used for Japanese).
+// The words are normalized to NFKD.
+func NewWordlist(words []string, sep string) (*Wordlist, error) {
+	if len(words) != 2048 {
+		return nil, InvWordlist
+	}
+	if sep == "" {
+		sep = " "
+	}
+	w := &Wordlist{words: make([]string, len(words)), index: make(map[string]int, len(words)), sep: sep}
+	for i, s := range words {
+		s = norm.NFKD.String(strings.TrimSpace(s))
+		if _, ok := w.index[s]; ok || s == "" {
+			return nil, InvWordlist
+		}
+		w.words[i] = s
+		w.index[s] = i
+	}
+	return w, nil
+}
+
+// Word returns the i-th word of w.
+func (w *Wordlist) Word(i int) string {
+	return w.words[i]
+}
+
+// NewMnemonic returns a new BIP39 mnemonic of bits (128, 160, 192, 224 or 256) bits of entropy from the words of wl.
+// The entropy is obtained from rand and mixed with the bytes obtained from ex (if len(ex) > 0), see RandFieldElementEx.
+func NewMnemonic(rand io.Reader, ex []byte, bits int, wl *Wordlist) (string, error) {
+	if bits < 128 || bits > 256 || bits%32 != 0 {
+		return "", InvEntropyLen
+	}
+	ent, err := randBytesEx(rand, ex, bits/8)
+	if err != nil {
+		return "", err
+	}
+	return MnemonicFromEntropy(ent, wl)
+}
+
+// MnemonicFromEntropy returns the BIP39 mnemonic of the entropy ent (16, 20, 24, 28 or 32 bytes) from the words of wl.
+func MnemonicFromEntropy(ent []byte, wl *Wordlist) (string, error) {
+	if len(ent) < 16 || len(ent) > 32 || len(ent)%4 != 0 {
+		return "", InvEntropyLen
+	}
+	if wl == nil {
+		wl = EnglishWordlist
+	}
+	cs := len(ent) / 4
+	h := sha256.Sum256(ent)
+	b := new(big.Int).SetBytes(ent)
+	b.Lsh(b, uint(cs))
+	b.Or(b, big.NewInt(int64(h[0]>>(8-cs))))
+	n := (len(ent)*8 + cs) / 11
+	words := make([]string, n)
+	m := big.NewInt(2047)
+	for i := n - 1; i >= 0; i-- {
+		words[i] = wl.words[new(big.Int).And(b, m).Int64()]
+		b.Rsh(b, 11)
+	}
+	return strings.Join(words, wl.sep), nil
+}
+
+// MnemonicToEntropy checks the mnemonic m (the words of wl) and returns its entropy.
+func MnemonicToEntropy(m string, wl *Wordlist) ([]byte, error) {
+	if wl == nil {
+		wl = EnglishWordlist
+	}
+	words := strings.Fields(norm.NFKD.String(m))
+	if len(words) < 12 || len(words) > 24 || len(words)%3 != 0 {
+		return nil, InvMnemonicLen
+	}
+	b := new(big.Int)
+	for _, w := range words {
+		i, ok := wl.index[w]
+		if !ok {
+			return nil, InvMnemonicW
+		}
+		b.Lsh(b, 11)
+		b.Or(b, big.NewInt(int64(i)))
+	}
+	cs := len(words) / 3
+	c := byte(b.Uint64() & (1<<cs - 1))
+	b.Rsh(b, uint(cs))
+	ent := make([]byte, (len(words)*11-cs)/8)
+	b.FillBytes(ent)
+	h := sha256.Sum256(ent)
+	if h[0]>>(8-cs) != c {
+		return nil, InvMnemonicCS
+	}
+	return ent, nil
+}
+
+// ValidateMnemonic returns nil if the mnemonic m is valid (the length, the words of wl and the checksum).
+func ValidateMnemonic(m string, wl *Wordlist) error {
+	_, err := MnemonicToEntropy(m, wl)
+	return err
+}
+
+// MnemonicToSeed returns the 64 bytes BIP39 seed of the mnemonic m and passphrase.
+// The mnemonic is not checked, use ValidateMnemonic to check it.
+func MnemonicToSeed(m, passphrase string) []byte {
+	m = strings.Join(strings.Fields(norm.NFKD.String(m)), " ")
+	s, _ := pbkdf2.Key(sha512.New, m, []byte("mnemonic"+norm.NFKD.String(passphrase)), 2048, 64)
+	return s
+}
@@ -0,0 +1,85 @@
+package cckat
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestBIP39Vectors checks the TREZOR test vectors (the passphrase "TREZOR"):
+// the entropy, the mnemonic, the seed and the master key of the seed.
+func TestBIP39Vectors(t *testing.T) {
+	b, err := os.ReadFile("testdata/bip39-vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v map[string][][4]string
+	if err := json.Unmarshal(b, &v); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range v["english"] {
+		ent := mustHex(t, c[0])
+		m, err := MnemonicFromEntropy(ent, nil)
+		if err != nil || m != c[1] {
+			t.Errorf("%s: got %q, %v, want %q", c[0], m, err, c[1])
+		}
+		if e, err := MnemonicToEntropy(c[1], EnglishWordlist); err != nil || !bytes.Equal(e, ent) {
+			t.Errorf("%s: entropy %x, %v", c[1], e, err)
+		}
+		seed := MnemonicToSeed(c[1], "TREZOR")
+		if hex.EncodeToString(seed) != c[2] {
+			t.Errorf("%s: seed %x, want %s", c[1], seed, c[2])
+		}
+		if k, err := NewMasterKey(seed, BTCMain); err != nil || k.String() != c[3] {
+			t.Errorf("%s: master key %v, %v, want %s", c[1], k, err, c[3])
+		}
+	}
+}
+
+func TestBIP39Invalid(t *testing.T) {
+	for _, c := range []struct {
+		name, m string
+		err     error
+	}{
+		{"checksum", "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon", InvMnemonicCS},
+		{"checksum 24", strings.Repeat("zoo ", 23) + "zoo", InvMnemonicCS},
+		{"13 words", "legal winner thank year wave sausage worth useful legal winner thank yellow yellow", InvMnemonicLen},
+		{"unknown word", "letter advice cage absurd amount doctor acoustic avoid letter advice caged above", InvMnemonicW},
+		{"11 words", "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", InvMnemonicLen},
+		{"27 words", strings.Repeat("abandon ", 26) + "art", InvMnemonicLen},
+		{"empty", "", InvMnemonicLen},
+	} {
+		if err := ValidateMnemonic(c.m, nil); err != c.err {
+			t.Errorf("%s: %v, want %v", c.name, err, c.err)
+		}
+	}
+	for _, n := range []int{0, 15, 17, 36} {
+		if _, err := MnemonicFromEntropy(make([]byte, n), nil); err != InvEntropyLen {
+			t.Errorf("entropy %d bytes: %v", n, err)
+		}
+	}
+}
+
+func TestNewMnemonic(t *testing.T) {
+	for _, bits := range []int{0, 96, 127, 130, 288} {
+		if _, err := NewMnemonic(rand.Reader, nil, bits, nil); err != InvEntropyLen {
+			t.Errorf("%d bits: %v", bits, err)
+		}
+	}
+	for bits := 128; bits <= 256; bits += 32 {
+		m, err := NewMnemonic(rand.Reader, nil, bits, nil)
+		if err != nil {
+			t.Fatalf("%d bits: %v", bits, err)
+		}
+		if n := len(strings.Fields(m)); n != bits*3/32 {
+			t.Errorf("%d bits: %d words", bits, n)
+		}
+		if err := ValidateMnemonic(m, nil); err != nil {
+			t.Errorf("%d bits: %v", bits, err)
+		}
+	}
+}
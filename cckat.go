@@ -8,7 +8,9 @@ import (
 	"golang.org/x/crypto/sha3"
 	"log"
 	"math/big"
+	"runtime"
 	"strings"
+	"sync"
 )
 
 var secp256k1 = Secp256k1()
@@ -18,6 +20,7 @@ type PrKey struct {
 	a      AddressType
 	uncomp bool
 	isset  bool
+	net    *Network
 }
 
 var (
@@ -29,8 +32,10 @@ var (
 	InvPubKeyF   = errors.New("invalid public key format")
 	InvWIFCSum   = errors.New("invalid WIF checksum")
 	InvAddrType  = errors.New("invalid address type")
+	PKeyNotSet   = errors.New("private key not set")
 	BIP38InvCSum = errors.New("BIP38 checksum invalid")
 	BIP38PassErr = errors.New("BIP38 wrong password")
+	BIP38InvKey  = errors.New("invalid BIP38 key")
 )
 
 // Set set k.k. to the value of key and returns k, nil. nil, PKeyOutOfR will be returned if k < 1 or k greater than the order of the base point - 1.
@@ -47,7 +52,7 @@ func (k *PrKey) Set(key big.Int) (*PrKey, error) {
 // If error != nil, (nil, error) returned.
 func (k *PrKey) SetHex(khex string) (*PrKey, error) {
 	khex = strings.TrimSpace(khex)
-	if khex[:2] == "0x" || khex[:2] == "0X" {
+	if strings.HasPrefix(khex, "0x") || strings.HasPrefix(khex, "0X") {
 		khex = khex[2:]
 	}
 	if len(khex) == 64 && isHex(khex) {
@@ -64,15 +69,21 @@ func (k *PrKey) SetBytes(b []byte) (*PrKey, error) {
 }
 
 // SetWIF interprets w as the WIF private key, sets k.k to that value, and returns k, error.
+// The network of k is set according to the version byte of w (see Networks).
+// Testnet, signet and regtest keys share the version byte and are detected as BTCTest,
+// use SetNetwork for signet or regtest.
 // If error != nil, (nil, error) returned.
 func (k *PrKey) SetWIF(w string) (*PrKey, error) {
 	w = strings.TrimSpace(w)
-	l := w[:1]
 	d, err := Base58Decode([]byte(w))
 	if err != nil {
 		return nil, err
 	}
-	if !(((l == `K` || l == `L`) && len(d) == 38) || (l == `5` && len(d) == 37)) {
+	if !(len(d) == 38 && d[33] == 0x01 || len(d) == 37) {
+		return nil, InvWIF
+	}
+	n := networkByWIF(d[0])
+	if n == nil {
 		return nil, InvWIF
 	}
 	csum := d[len(d)-4:]
@@ -80,19 +91,16 @@ func (k *PrKey) SetWIF(w string) (*PrKey, error) {
 	if string(csum) != string(checksum(d)) {
 		return nil, InvWIFCSum
 	}
-	if l == `K` || l == `L` {
-		d = d[:len(d)-1]
-	} else {
-		k.uncomp = true
-	}
-	return k.Set(*new(big.Int).SetBytes(d[1:]))
+	k.uncomp = len(d) == 33
+	k.net = n
+	return k.Set(*new(big.Int).SetBytes(d[1:33]))
 }
 
 // SetBIP38 tries to decrypt the BIP38 encrypted private key w with a password p, sets k.k to its value and returns k, error.
 // If error != nil, (nil, error) returned.
 func (k *PrKey) SetBIP38(w, p string) (*PrKey, error) {
 	w = strings.TrimSpace(w)
-	t, err := Decrypt(w, p)
+	t, err := decryptNet(w, p, k.Network())
 	if err != nil {
 		return nil, err
 	}
@@ -112,69 +120,129 @@ func (k *PrKey) SetAddressType(t AddressType) (*PrKey, error) {
 
 // BIP38 returns the BIP38 encoded private key k.k.
 func (k *PrKey) BIP38(p string) string {
-	k.checkIsSet()
-	return Encrypt(*k, p)
+	b, err := k.BIP38c(p)
+	fatalIf(err)
+	return b
+}
+
+// BIP38c returns the BIP38 encoded private key k.k or PKeyNotSet error.
+func (k *PrKey) BIP38c(p string) (string, error) {
+	if err := k.isSet(); err != nil {
+		return "", err
+	}
+	return Encrypt(*k, p), nil
 }
 
 // Bytes returns the private key as 32 byte slice.
 func (k *PrKey) Bytes() []byte {
-	k.checkIsSet()
-	return bytesFull(&k.k)
+	b, err := k.Bytesc()
+	fatalIf(err)
+	return b
+}
+
+// Bytesc returns the private key as 32 byte slice or PKeyNotSet error.
+func (k *PrKey) Bytesc() ([]byte, error) {
+	if err := k.isSet(); err != nil {
+		return nil, err
+	}
+	return bytesFull(&k.k), nil
 }
 
 // WIF returns the private key in WIF format.
 func (k *PrKey) WIF() string {
-	k.checkIsSet()
+	w, err := k.WIFc()
+	fatalIf(err)
+	return w
+}
+
+// WIFc returns the private key in WIF format or PKeyNotSet error.
+func (k *PrKey) WIFc() (string, error) {
+	if err := k.isSet(); err != nil {
+		return "", err
+	}
 	pk := bytesFull(&k.k)
 	a := make([]byte, 1, 39)
-	a[0] = 0x80
+	a[0] = k.Network().PrivateKey
 	a = append(a, pk...)
 	if !k.uncomp {
 		a = append(a, 0x01)
 	}
 	a = append(a, checksum(a)...)
-	return string(Base58Encode(a))
+	return string(Base58Encode(a)), nil
 }
 
 // Hex returns the private key in HEX format.
 func (k *PrKey) Hex() string {
-	k.checkIsSet()
-	return fmt.Sprintf("%X", bytesFull(&k.k))
+	h, err := k.Hexc()
+	fatalIf(err)
+	return h
 }
 
-// PubK returns the uncompressed public key
-func (k *PrKey) PubK() []byte {
-	k.checkIsSet()
-	return PubKey(&k.k, true)
+// Hexc returns the private key in HEX format or PKeyNotSet error.
+func (k *PrKey) Hexc() (string, error) {
+	if err := k.isSet(); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%X", bytesFull(&k.k)), nil
 }
 
-/*
-func (k *PrKey) PubK() []byte {
-	k.checkIsSet()
-	return PubK(&k.k, true)
+// PubK returns the public key.
+func (k *PrKey) PubK() PublicKey {
+	p, err := k.PubKc()
+	fatalIf(err)
+	return p
 }
 
-
-func (k *PrKey) PubKeyUncomp() []byte {
-	return PubK(&k.k, true)
+// PubKc returns the public key or PKeyNotSet error.
+func (k *PrKey) PubKc() (PublicKey, error) {
+	if err := k.isSet(); err != nil {
+		return nil, err
+	}
+	return PubKey(&k.k, true), nil
 }
-*/
 
 // Address returns the address of the type k.a
-func (k *PrKey) Address() (a string) {
-	k.checkIsSet()
-	a, _ = addresses[k.a](k.PubK())
-	return
+func (k *PrKey) Address() string {
+	a, err := k.Addressc()
+	fatalIf(err)
+	return a
+}
+
+// Addressc returns the address of the type k.a or an error.
+func (k *PrKey) Addressc() (string, error) {
+	return k.AddressTc(k.a)
 }
 
 // AddressT returns an address of the given type
-func (k *PrKey) AddressT(at AddressType) (a string) {
-	k.checkIsSet()
+func (k *PrKey) AddressT(at AddressType) string {
+	a, err := k.AddressTc(at)
+	fatalIf(err)
+	return a
+}
+
+// AddressTc returns an address of the given type or an error (PKeyNotSet, InvAddrType).
+func (k *PrKey) AddressTc(at AddressType) (string, error) {
+	if err := k.isSet(); err != nil {
+		return "", err
+	}
 	if !checkAddressType(at) {
-		log.Fatal("Invalid address type.")
+		return "", InvAddrType
 	}
-	a, _ = addresses[at](k.PubK())
-	return
+	return addresses[at](PubKey(&k.k, true), k.Network())
+}
+
+// SetNetwork sets the network of k (used for WIF and addresses) and returns k.
+func (k *PrKey) SetNetwork(n *Network) *PrKey {
+	k.net = n
+	return k
+}
+
+// Network returns the network of k. BTCMain is returned if the network is not set.
+func (k *PrKey) Network() *Network {
+	if k.net == nil {
+		return BTCMain
+	}
+	return k.net
 }
 
 func (k *PrKey) GetAddressType() AddressType {
@@ -190,17 +258,27 @@ func (k *PrKey) SetUncomp(u bool) *PrKey {
 	return k
 }
 
-func (k *PrKey) checkIsSet() {
+// isSet returns PKeyNotSet if the private key has not been set.
+func (k *PrKey) isSet() error {
 	if !k.isset {
-		log.Fatal("Private key not set.")
+		return PKeyNotSet
+	}
+	return nil
+}
+
+// fatalIf terminates the program if err != nil.
+// Used by the methods which do not return an error.
+func fatalIf(err error) {
+	if err != nil {
+		log.Fatal(err)
 	}
 }
 
 func checkAddressType(t AddressType) bool {
-	return t < MaxType
+	return t < MaxType && addresses[t] != nil
 }
 
-// PubKey returns the public key of the private key k in compressed format if comp == true or in uncompressed format if comp == false.
+// PubKey returns the public key of the private key k in uncompressed format if uncomp == true or in compressed format if uncomp == false.
 func PubKey(k *big.Int, uncomp bool) []byte {
 	px, py := secp256k1.ScalarBaseMult(k.Bytes())
 	bx := bytesFull(px)
@@ -216,40 +294,56 @@ func PubKey(k *big.Int, uncomp bool) []byte {
 		f |= 0x1
 	}
 	res := make([]byte, 1, 33)
-	res[1] = f
+	res[0] = f
 	return append(res, bx...)
 }
 
-// PubKeyCompUncomp returns public key in compressed format if comp == true or in uncompressed format if comp == false.
-// k - public key in compressed or uncompressed format.
-// Returns nil and InvPubKeyF error if the public key format is invalid.
-// WARNING!!!  This function checks only the key format. If the point is not on the
-// curve (or at infinity), the behavior is undefined. Use the IsOnCurve function to check this.
-func PubKeyCompUncomp(k []byte, comp bool) ([]byte, error) {
-	if k[0] == 0x04 && len(k) == 65 {
-		if comp {
-			f := byte(0x2)
-			if k[64]&1 == 1 {
-				f |= 0x1
-			}
-			res := make([]byte, 1, 33)
-			res[0] = f
-			return append(res, k[1:33]...), nil
+// PubKeys returns the public keys of the private keys ks (the same as PubKey for each key).
+// It is faster for many keys: the points are converted to affine coordinates together with one field inversion
+// per goroutine (Montgomery's simultaneous inversion) and the keys are split between GOMAXPROCS goroutines.
+func PubKeys(ks []*big.Int, uncomp bool) [][]byte {
+	res := make([][]byte, len(ks))
+	chunk := max((len(ks)+runtime.GOMAXPROCS(0)-1)/runtime.GOMAXPROCS(0), 64)
+	var wg sync.WaitGroup
+	for i := 0; i < len(ks); i += chunk {
+		j := min(i+chunk, len(ks))
+		wg.Go(func() {
+			pubKeys(ks[i:j], uncomp, res[i:j])
+		})
+	}
+	wg.Wait()
+	return res
+}
 
-		}
-		return k, nil
+// pubKeys sets res to the public keys of ks.
+func pubKeys(ks []*big.Int, uncomp bool, res [][]byte) {
+	ps := make([]point, len(ks))
+	for i, k := range ks {
+		s := scalarBytes(k.Bytes())
+		ps[i].scalarBaseMult(&s)
 	}
-	if (k[0] == 0x03 || k[0] == 0x02) && len(k) == 33 {
-		if comp {
-			return k, nil
+	for i, a := range batchAffine(ps) {
+		if uncomp {
+			res[i] = append([]byte{0x04}, a[:]...)
+		} else {
+			res[i] = append([]byte{0x02 | a[63]&1}, a[:32]...)
 		}
-		_, y := PointFromX(k[1:], k[0] == 0x2)
-		ret := make([]byte, 1, 65)
-		ret[0] = 0x4
-		ret = append(ret, k[1:]...)
-		return append(ret, bytesFull(y)...), nil
 	}
-	return nil, InvPubKeyF
+}
+
+// PubKeyCompUncomp returns public key in compressed format if comp == true or in uncompressed format if comp == false.
+// k - public key in any format accepted by ParsePubKey.
+// Returns nil and InvPubKeyF error if the public key format is invalid,
+// CoordOutOfR or NoSuchPoin if the point is not on the curve.
+func PubKeyCompUncomp(k []byte, comp bool) ([]byte, error) {
+	p, err := ParsePubKey(k)
+	if err != nil {
+		return nil, err
+	}
+	if comp {
+		return p.Compressed(), nil
+	}
+	return p.Uncompressed(), nil
 }
 
 // HashPubKey returns the Hash160 hash of the pubkey.
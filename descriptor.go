@@ -0,0 +1,533 @@
+package cckat
+
+import (
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var (
+	InvDescriptor   = errors.New("invalid output descriptor")
+	InvDescCSum     = errors.New("invalid output descriptor checksum")
+	UnsupDescriptor = errors.New("unsupported output descriptor")
+)
+
+const (
+	descInputCharset    = "0123456789()[],'/*abcdefgh@:$%{}IJKLMNOPQRSTUVWXYZ&+-.;<=>?!^_|~ijklmnopqrstuvwxyzABCDEFGH`#\"\\ "
+	descChecksumCharset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+)
+
+// descPolymod is the checksum function of the output descriptors (BIP380).
+func descPolymod(c uint64, v int) uint64 {
+	top := c >> 35
+	c = (c&0x7ffffffff)<<5 ^ uint64(v)
+	for i, g := range [5]uint64{0xf5dee51989, 0xa9fdca3312, 0x1bab10e32d, 0x3706b1677a, 0x644d626ffd} {
+		if top>>i&1 != 0 {
+			c ^= g
+		}
+	}
+	return c
+}
+
+// DescriptorChecksum returns the 8 character checksum of the output descriptor desc given without "#checksum" (BIP380).
+func DescriptorChecksum(desc string) (string, error) {
+	c := uint64(1)
+	var cls, clsCount int
+	for i := 0; i < len(desc); i++ {
+		p := strings.IndexByte(descInputCharset, desc[i])
+		if p < 0 {
+			return "", InvDescriptor
+		}
+		c = descPolymod(c, p&31)
+		cls = cls*3 + p>>5
+		if clsCount++; clsCount == 3 {
+			c = descPolymod(c, cls)
+			cls, clsCount = 0, 0
+		}
+	}
+	if clsCount > 0 {
+		c = descPolymod(c, cls)
+	}
+	for range 8 {
+		c = descPolymod(c, 0)
+	}
+	c ^= 1
+	res := make([]byte, 8)
+	for i := range res {
+		res[i] = descChecksumCharset[c>>(5*(7-i))&31]
+	}
+	return string(res), nil
+}
+
+// descCtx is the context of the script expression: the top level, inside sh(), wsh() or a tr() script tree.
+type descCtx int
+
+const (
+	descTop descCtx = iota
+	descSH
+	descWSH
+	descTap
+)
+
+// descKey is the key expression of the output descriptor (BIP380).
+type descKey struct {
+	pub      []byte       // the fixed public key, nil for the extended key
+	ext      *ExtendedKey // the extended key derived by the path given after it
+	wildcard bool         // the path ends with /*
+	hardened uint32       // HardenedKeyStart for /*' or /*h
+	xOnly    bool         // the X-only key is used (tr)
+}
+
+// pubKey returns the public key of k for the index i of the ranged descriptor.
+func (k *descKey) pubKey(i uint32) ([]byte, error) {
+	if k.ext == nil {
+		return k.pub, nil
+	}
+	e := k.ext
+	if k.wildcard {
+		if i >= HardenedKeyStart {
+			return nil, InvPath
+		}
+		var err error
+		if e, err = e.Child(i | k.hardened); err != nil {
+			return nil, err
+		}
+	}
+	p := e.PubKey().Compressed()
+	if k.xOnly {
+		p = p[1:]
+	}
+	return p, nil
+}
+
+// descNode is the script expression of the output descriptor.
+type descNode struct {
+	name string // pk, pkh, wpkh, sh, wsh, multi, sortedmulti or tr
+	keys []*descKey
+	m    int       // multi threshold
+	sub  *descNode // sh and wsh script
+	tree *descTree // tr script tree, nil for key path only
+}
+
+// descTree is the script tree of tr(): the leaf script or the branch of left and right.
+type descTree struct {
+	leaf        *descNode
+	left, right *descTree
+}
+
+// Descriptor is the output script descriptor (BIP380-386) of the forms pk(KEY), pkh(KEY), wpkh(KEY),
+// sh(wpkh(KEY)), multi(k,KEY,...), sortedmulti(k,KEY,...) bare or inside sh(), wsh() or sh(wsh()), and
+// tr(KEY) or tr(KEY,TREE) with pk(KEY) leaves; the tapscript multisig leaves multi_a() and sortedmulti_a()
+// (BIP387) are not supported. KEY is the hex public key, WIF or xpub/xprv (of any network)
+// with the optional key origin [fingerprint/path] and the derivation path, which may end with /* or /*'.
+// See https://github.com/bitcoin/bips/blob/master/bip-0380.mediawiki
+type Descriptor struct {
+	desc   string
+	root   *descNode
+	ranged bool
+}
+
+// ParseDescriptor parses the output descriptor s. The checksum is verified if s ends with "#checksum".
+func ParseDescriptor(s string) (*Descriptor, error) {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '#'); i >= 0 {
+		c, err := DescriptorChecksum(s[:i])
+		if err != nil {
+			return nil, err
+		}
+		if s[i+1:] != c {
+			return nil, InvDescCSum
+		}
+		s = s[:i]
+	} else if _, err := DescriptorChecksum(s); err != nil {
+		return nil, err
+	}
+	d := &Descriptor{desc: s}
+	var err error
+	if d.root, err = d.parseScript(s, descTop); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// descCall splits s of the form name(args) into name and the top level comma separated args.
+func descCall(s string) (string, []string, bool) {
+	i := strings.IndexByte(s, '(')
+	if i < 0 || s[len(s)-1] != ')' {
+		return "", nil, false
+	}
+	args, ok := descSplit(s[i+1 : len(s)-1])
+	return s[:i], args, ok
+}
+
+// descSplit splits s at the commas outside of the brackets.
+func descSplit(s string) ([]string, bool) {
+	var res []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(', '{', '[':
+			depth++
+		case ')', '}', ']':
+			if depth--; depth < 0 {
+				return nil, false
+			}
+		case ',':
+			if depth == 0 {
+				res = append(res, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(res, s[start:]), depth == 0
+}
+
+func (d *Descriptor) parseScript(s string, ctx descCtx) (*descNode, error) {
+	name, args, ok := descCall(s)
+	if !ok {
+		return nil, InvDescriptor
+	}
+	n := &descNode{name: name}
+	var err error
+	switch {
+	case name == "pk" || (name == "pkh" || name == "wpkh") && ctx != descTap:
+		if len(args) != 1 || name == "wpkh" && ctx != descTop && ctx != descSH {
+			return nil, InvDescriptor
+		}
+		k, err := d.parseKey(args[0], ctx == descTap, name != "wpkh" && (ctx == descTop || ctx == descSH))
+		if err != nil {
+			return nil, err
+		}
+		n.keys = []*descKey{k}
+	case name == "sh" && ctx == descTop:
+		if len(args) != 1 {
+			return nil, InvDescriptor
+		}
+		if n.sub, err = d.parseScript(args[0], descSH); err != nil {
+			return nil, err
+		}
+	case name == "wsh" && (ctx == descTop || ctx == descSH):
+		if len(args) != 1 {
+			return nil, InvDescriptor
+		}
+		if n.sub, err = d.parseScript(args[0], descWSH); err != nil {
+			return nil, err
+		}
+	case (name == "multi" || name == "sortedmulti") && ctx != descTap:
+		if len(args) < 2 {
+			return nil, InvDescriptor
+		}
+		if n.m, err = strconv.Atoi(args[0]); err != nil || args[0][0] == '+' || args[0][0] == '-' {
+			return nil, InvDescriptor
+		}
+		maxKeys := 16
+		switch ctx {
+		case descTop:
+			maxKeys = 3
+		case descWSH:
+			maxKeys = 20
+		}
+		if n.m < 1 || n.m > len(args)-1 || len(args)-1 > maxKeys {
+			return nil, InvMultisig
+		}
+		for _, a := range args[1:] {
+			k, err := d.parseKey(a, false, ctx != descWSH)
+			if err != nil {
+				return nil, err
+			}
+			n.keys = append(n.keys, k)
+		}
+	case name == "tr" && ctx == descTop:
+		if len(args) != 1 && len(args) != 2 {
+			return nil, InvDescriptor
+		}
+		k, err := d.parseKey(args[0], true, false)
+		if err != nil {
+			return nil, err
+		}
+		n.keys = []*descKey{k}
+		if len(args) == 2 {
+			if n.tree, err = d.parseTree(args[1], 0); err != nil {
+				return nil, err
+			}
+		}
+	case (name == "multi_a" || name == "sortedmulti_a") && ctx == descTap:
+		return nil, UnsupDescriptor
+	default:
+		return nil, InvDescriptor
+	}
+	return n, nil
+}
+
+// parseTree parses the script tree TREE of tr(KEY,TREE): the script or {TREE,TREE}.
+func (d *Descriptor) parseTree(s string, depth int) (*descTree, error) {
+	if depth > maxTapTreeDepth {
+		return nil, InvTapTree
+	}
+	if !strings.HasPrefix(s, "{") {
+		n, err := d.parseScript(s, descTap)
+		if err != nil {
+			return nil, err
+		}
+		return &descTree{leaf: n}, nil
+	}
+	if !strings.HasSuffix(s, "}") {
+		return nil, InvDescriptor
+	}
+	args, ok := descSplit(s[1 : len(s)-1])
+	if !ok || len(args) != 2 {
+		return nil, InvDescriptor
+	}
+	l, err := d.parseTree(args[0], depth+1)
+	if err != nil {
+		return nil, err
+	}
+	r, err := d.parseTree(args[1], depth+1)
+	if err != nil {
+		return nil, err
+	}
+	return &descTree{left: l, right: r}, nil
+}
+
+// parseKey parses the key expression s. The X-only keys are used if xOnly is true (tr),
+// the uncompressed keys are allowed only if uncomp is true (not in the segwit scripts).
+func (d *Descriptor) parseKey(s string, xOnly, uncomp bool) (*descKey, error) {
+	if strings.HasPrefix(s, "[") {
+		i := strings.IndexByte(s, ']')
+		if i < 0 {
+			return nil, InvDescriptor
+		}
+		fp, path, _ := strings.Cut(s[1:i], "/")
+		if len(fp) != 8 || !isHex(fp) {
+			return nil, InvDescriptor
+		}
+		if path != "" {
+			if _, err := ParsePath(path); err != nil || strings.HasPrefix(path, "m") {
+				return nil, InvPath
+			}
+		}
+		s = s[i+1:]
+	}
+	k := &descKey{xOnly: xOnly}
+	key, path, hasPath := strings.Cut(s, "/")
+	switch {
+	case isHex(key) && len(key) == 64 && xOnly, isHex(key) && (len(key) == 66 || len(key) == 130 && uncomp && !xOnly):
+		if hasPath {
+			return nil, InvDescriptor
+		}
+		b, _ := hex.DecodeString(key)
+		p, err := ParsePubKey(b)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) == 65 && b[0] != 0x04 {
+			return nil, InvPubKeyF
+		}
+		k.pub = b
+		if xOnly {
+			k.pub = p.XOnly()
+		}
+		return k, nil
+	case isHex(key):
+		return nil, InvPubKeyF
+	}
+	if w, err := new(PrKey).SetWIF(key); err == nil {
+		if hasPath || w.uncomp && (!uncomp || xOnly) {
+			return nil, InvDescriptor
+		}
+		k.pub = PubKey(&w.k, w.uncomp)
+		if xOnly {
+			k.pub = k.pub[1:]
+		}
+		return k, nil
+	}
+	e, err := ParseExtendedKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if hasPath {
+		parts := strings.Split(path, "/")
+		switch l := len(parts) - 1; parts[l] {
+		case "*'", "*h":
+			if !e.IsPrivate() {
+				return nil, HardenedPub
+			}
+			k.hardened = HardenedKeyStart
+			fallthrough
+		case "*":
+			k.wildcard = true
+			d.ranged = true
+			parts = parts[:l]
+		}
+		if len(parts) > 0 {
+			p, err := ParsePath(strings.Join(parts, "/"))
+			if err != nil || parts[0] == "" || strings.HasPrefix(parts[0], "m") {
+				return nil, InvPath
+			}
+			if e, err = e.DerivePath(p); err != nil {
+				return nil, err
+			}
+		}
+	}
+	k.ext = e
+	return k, nil
+}
+
+// String returns the output descriptor d with its checksum.
+func (d *Descriptor) String() string {
+	c, _ := DescriptorChecksum(d.desc)
+	return d.desc + "#" + c
+}
+
+// IsRange returns true if d has the key with the wildcard (/*), so its scripts depend on the index.
+func (d *Descriptor) IsRange() bool {
+	return d.ranged
+}
+
+// Script returns the output script (scriptPubKey) of d for the index i (ignored if d is not ranged).
+func (d *Descriptor) Script(i uint32) ([]byte, error) {
+	return d.root.script(i)
+}
+
+// Address returns the address of d for the index i (ignored if d is not ranged) and the network n.
+// Returns UnsupAddrType for the bare pk() and multi() scripts which have no address.
+func (d *Descriptor) Address(i uint32, n *Network) (string, error) {
+	r := d.root
+	switch r.name {
+	case "pkh", "wpkh":
+		k, err := r.keys[0].pubKey(i)
+		if err != nil {
+			return "", err
+		}
+		t := P2WPKH
+		if r.name == "pkh" {
+			t = P2PKH
+			if len(k) == 65 {
+				t = P2PKHUncomp
+			}
+		}
+		return GetAddress(k, t, n)
+	case "sh", "wsh":
+		s, err := r.sub.script(i)
+		if err != nil {
+			return "", err
+		}
+		if r.name == "sh" {
+			return ScriptAddressP2SH(s, n)
+		}
+		return ScriptAddressP2WSH(s, n)
+	case "tr":
+		k, t, err := r.tapOutput(i)
+		if err != nil {
+			return "", err
+		}
+		return ScriptAddressP2TR(k, t, n)
+	}
+	return "", UnsupAddrType
+}
+
+// Addresses returns the addresses of d for the indexes from start to end inclusive and the network n.
+// Only the one address is returned if d is not ranged.
+func (d *Descriptor) Addresses(n *Network, start, end uint32) ([]string, error) {
+	if start > end || end >= HardenedKeyStart {
+		return nil, InvPath
+	}
+	if !d.ranged {
+		end = start
+	}
+	var res []string
+	for i := start; i <= end; i++ {
+		a, err := d.Address(i, n)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, a)
+	}
+	return res, nil
+}
+
+// script returns the output script of the expression n for the index i, the redeem or witness script inside sh() or wsh().
+func (n *descNode) script(i uint32) ([]byte, error) {
+	keys := make([][]byte, len(n.keys))
+	for j, k := range n.keys {
+		p, err := k.pubKey(i)
+		if err != nil {
+			return nil, err
+		}
+		keys[j] = p
+	}
+	switch n.name {
+	case "pk":
+		return append(appendPush(nil, keys[0]), 0xac), nil
+	case "pkh":
+		return p2pkhScript(HashPubKey(keys[0])), nil
+	case "wpkh":
+		return witnessScript(0, HashPubKey(keys[0])), nil
+	case "sh":
+		s, err := n.sub.script(i)
+		if err != nil {
+			return nil, err
+		}
+		if len(s) > maxScriptElementSize {
+			return nil, InvScriptLen
+		}
+		return p2shScript(HashPubKey(s)), nil
+	case "wsh":
+		s, err := n.sub.script(i)
+		if err != nil {
+			return nil, err
+		}
+		if len(s) > maxWitnessScriptSize {
+			return nil, InvScriptLen
+		}
+		return witnessScript(0, sha256Sum(s)), nil
+	case "multi", "sortedmulti":
+		return multisigScript(n.m, keys, n.name == "sortedmulti")
+	case "tr":
+		k, t, err := n.tapOutput(i)
+		if err != nil {
+			return nil, err
+		}
+		q, _, err := TapOutput(k, t)
+		if err != nil {
+			return nil, err
+		}
+		return witnessScript(1, q), nil
+	}
+	return nil, InvDescriptor
+}
+
+// tapOutput returns the internal key and the script tree of tr() for the index i.
+func (n *descNode) tapOutput(i uint32) ([]byte, *TapTree, error) {
+	k, err := n.keys[0].pubKey(i)
+	if err != nil {
+		return nil, nil, err
+	}
+	if n.tree == nil {
+		return k, nil, nil
+	}
+	t, err := n.tree.tapTree(i)
+	return k, t, err
+}
+
+// tapTree returns the taproot script tree of t for the index i.
+func (t *descTree) tapTree(i uint32) (*TapTree, error) {
+	if t.leaf != nil {
+		s, err := t.leaf.script(i)
+		if err != nil {
+			return nil, err
+		}
+		l := NewTapLeaf(s)
+		return &TapTree{Leaf: &l}, nil
+	}
+	l, err := t.left.tapTree(i)
+	if err != nil {
+		return nil, err
+	}
+	r, err := t.right.tapTree(i)
+	if err != nil {
+		return nil, err
+	}
+	return &TapTree{Left: l, Right: r}, nil
+}
@@ -0,0 +1,183 @@
+package cckat
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+const (
+	g2X = "c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5"
+	// the internal key of the BIP386 test vectors
+	tapKey = "a34b99f22c790c4e36b2b3c2c35a36db06226e41c692fc82b8b56ac1c540c5bd"
+	// the root key of the BIP84, BIP86 etc. test mnemonic "abandon abandon ... about"
+	abandonXprv = "xprv9s21ZrQH143K3GJpoapnV8SFfukcVBSfeCficPSGfubmSFDxo1kuHnLisriDvSnRRuL2Qrg5ggqHKNVpxR86QEC8w35uxmGoggxtQTPvfUu"
+)
+
+func TestDescriptorChecksum(t *testing.T) {
+	for desc, want := range map[string]string{
+		"raw(deadbeef)":                            "89f8spxm",
+		"pkh(02" + g2X + ")":                       "8fhd9pwu",
+		"addr(mkmZxiEcEd8ZqjQWVZuC6so5dFMKEFpN2j)": "02wpgw69",
+	} {
+		if c, err := DescriptorChecksum(desc); err != nil || c != want {
+			t.Errorf("%s: got %s, %v, want %s", desc, c, err, want)
+		}
+	}
+	if _, err := DescriptorChecksum("raw(Ü)"); err != InvDescriptor {
+		t.Errorf("non-ASCII: %v", err)
+	}
+
+	d := "pkh(02" + g2X + ")"
+	if _, err := ParseDescriptor(d + "#8fhd9pwu"); err != nil {
+		t.Errorf("valid checksum: %v", err)
+	}
+	for name, s := range map[string]string{
+		"empty checksum":   d + "#",
+		"missing payload":  "#8fhd9pwu",
+		"too long":         d + "#8fhd9pwuq",
+		"too short":        d + "#8fhd9pw",
+		"checksum error":   d + "#8fhd9pwv",
+		"payload error":    "pkh(03" + g2X + ")#8fhd9pwu",
+		"second separator": d + "#8fhd#9pwu",
+	} {
+		if _, err := ParseDescriptor(s); err != InvDescCSum && err != InvDescriptor {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestDescriptorAddresses(t *testing.T) {
+	for _, c := range []struct {
+		desc   string
+		script string
+		addrs  []string
+	}{
+		{"pkh(02" + g2X + ")", "76a91406afd46bcdfd22ef94ac122aa11f241244a37ecc88ac", nil},
+		{"tr(" + tapKey + ")", "512077aab6e066f8a7419c5ab714c12c67d25007ed55a43cadcacb4d7a970a093f11",
+			[]string{"bc1pw74tdcrxlzn5r8z6ku2vztr86fgq0m245s72mjktf4afwzsf8ugs0gs8zu"}},
+		{"tr(02" + tapKey + ")", "512077aab6e066f8a7419c5ab714c12c67d25007ed55a43cadcacb4d7a970a093f11", nil},
+		{"pkh(" + abandonXprv + "/44'/0'/0'/0/*)", "", []string{"1LqBGSKuX5yYUonjxT5qGfpUsXKYYWeabA"}},
+		{"sh(wpkh(" + abandonXprv + "/49'/0'/0'/0/*))", "", []string{"37VucYSaXLCAsxYyAPfbSi9eh4iEcbShgf"}},
+		{"wpkh(" + abandonXprv + "/84'/0'/0'/0/*)", "", []string{"bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu"}},
+		{"tr(" + abandonXprv + "/86'/0'/0'/0/*)", "", []string{"bc1p5cyxnuxmeuwuvkwfem96lqzszd02n6xdcjrs20cac6yqjjwudpxqkedrcr"}},
+	} {
+		d, err := ParseDescriptor(c.desc)
+		if err != nil {
+			t.Errorf("%s: %v", c.desc, err)
+			continue
+		}
+		if c.script != "" {
+			if s, err := d.Script(0); err != nil || hex.EncodeToString(s) != c.script {
+				t.Errorf("%s: script %x, %v, want %s", c.desc, s, err, c.script)
+			}
+		}
+		if c.addrs != nil {
+			a, err := d.Addresses(BTCMain, 0, uint32(len(c.addrs)-1))
+			if err != nil || strings.Join(a, " ") != strings.Join(c.addrs, " ") {
+				t.Errorf("%s: addresses %v, %v, want %v", c.desc, a, err, c.addrs)
+			}
+		}
+		if p, err := ParseDescriptor(d.String()); err != nil || p.String() != d.String() {
+			t.Errorf("%s: round trip %v", d, err)
+		}
+	}
+}
+
+// The test vectors of BIP381-384, the scripts of the indexes 0, 1, 2 of the ranged descriptors
+// (the scripts of sh(wsh(sortedmulti())) are computed by btcd).
+func TestDescriptorVectors(t *testing.T) {
+	const (
+		wif  = "L4rK1yDtCWekvXuE6oXD9jCYfFNV2cWRpVuPLBcCU2z8TrisoyY1"
+		wifU = "5KYZdUEo39z3FPrtuX2QbbwGnNP5zTd7yyr2SC1j299sBCnWjss"
+		xA   = "xprvA1RpRA33e1JQ7ifknakTFpgNXPmW2YvmhqLQYMmrj4xJXXWYpDPS3xz7iAxn8L39njGVyuoseXzU6rcxFLJ8HFsTjSyQbLYnMpCqE2VbFWc"
+		xB   = "xprv9uPDJpEQgRQfDcW7BkF7eTya6RPxXeJCqCJGHuCJ4GiRVLzkTXBAJMu2qaMWPrS7AANYqdq6vcBcBUdJCVVFceUvJFjaPdGZ2y9WACViL4L"
+		xC   = "xprv9s21ZrQH143K31xYSDQpPDxsXRTUcvj2iNHm5NUtrGiGG5e2DtALGdso3pGz6ssrdK4PFmM8NSpSBHNqPqm55Qn3LqFtT2emdEXVYsCzC2U"
+		xD   = "xprv9vHkqa6EV4sPZHYqZznhT2NPtPCjKuDKGY38FBWLvgaDx45zo9WQRUT3dKYnjwih2yJD9mkrocEZXo1ex8G81dwSM1fwqWpWkeS3v86pgKt"
+		xE   = "xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi"
+		xpF  = "xpub661MyMwAqRbcFW31YEwpkMuc5THy2PSt5bDMsktWQcFF8syAmRUapSCGu8ED9W6oDMSgv6Zz8idoc4a6mr8BDzTJY47LJhkJ8UB7WEGuduB"
+		xpG  = "xpub69H7F5d8KSRgmmdJg2KhpAK8SR3DjMwAdkxj3ZuxV27CprR9LgpeyGmXUbC6wb7ERfvrnKZjXoUmmDznezpbZb7ap6r1D3tgFxHmwMkQTPH"
+		bare = "512103a34b99f22c790c4e36b2b3c2c35a36db06226e41c692fc82b8b56ac1c540c5bd4104a34b99f22c790c4e36b2b3c2c35a36db06226e41c692fc82b8b56ac1c540c5bd5b8dec5235a0fa8722476c7709c02559e3aa73aa03918ba2d492eea75abea23552ae"
+	)
+	for _, c := range []struct {
+		desc    string
+		scripts []string
+	}{
+		{"pk(" + wif + ")", []string{"2103a34b99f22c790c4e36b2b3c2c35a36db06226e41c692fc82b8b56ac1c540c5bdac"}},
+		{"pkh([deadbeef/1/2'/3/4']" + wif + ")", []string{"76a9149a1c78a507689f6f54b847ad1cef1e614ee23f1e88ac"}},
+		{"pkh([deadbeef/1/2h/3/4h]03" + tapKey + ")", []string{"76a9149a1c78a507689f6f54b847ad1cef1e614ee23f1e88ac"}},
+		{"wpkh(" + wif + ")", []string{"00149a1c78a507689f6f54b847ad1cef1e614ee23f1e"}},
+		{"wpkh([ffffffff/13']" + xD + "/1/2/*)", []string{
+			"0014326b2249e3a25d5dc60935f044ee835d090ba859",
+			"0014af0bd98abc2f2cae66e36896a39ffe2d32984fb7",
+			"00141fa798efd1cbf95cebf912c031b8a4a6e9fb9f27"}},
+		{"sh(wpkh(" + xE + "/10/20/30/40/*h))", []string{
+			"a9149a4d9901d6af519b2a23d4a2f51650fcba87ce7b87",
+			"a914bed59fc0024fae941d6e20a3b44a109ae740129287",
+			"a9148483aa1116eb9c05c482a72bada4b1db24af654387"}},
+		{"multi(1," + wif + "," + wifU + ")", []string{bare}},
+		{"sortedmulti(1," + wifU + "," + wif + ")", []string{bare}},
+		{"sh(multi(2,[00000000/111'/222]" + xA + "," + xB + "/0))", []string{"a91445a9a622a8b0a1269944be477640eedc447bbd8487"}},
+		{"sh(sortedmulti(2," + xB + "/0,[00000000/111'/222]" + xA + "))", []string{"a91445a9a622a8b0a1269944be477640eedc447bbd8487"}},
+		{"wsh(multi(2," + xC + "/2147483647'/0," + xD + "/1/2/*," + xE + "/10/20/30/40/*'))", []string{
+			"0020b92623201f3bb7c3771d45b2ad1d0351ea8fbf8cfe0a0e570264e1075fa1948f",
+			"002036a08bbe4923af41cf4316817c93b8d37e2f635dd25cfff06bd50df6ae7ea203",
+			"0020a96e7ab4607ca6b261bfe3245ffda9c746b28d3f59e83d34820ec0e2b36c139c"}},
+		{"sh(wsh(sortedmulti(1," + xpF + "/1/0/*," + xpG + "/0/0/*)))", []string{
+			"a9148ed5520532d2cf035f9c661e6d6ba8d0722ac87687",
+			"a914dbcd6f6cb308f2fafc7ba2b2fe43d91683fde61d87",
+			"a914e111000e9e15b213ed12a9049a2bccd6359f113887"}},
+	} {
+		d, err := ParseDescriptor(c.desc)
+		if err != nil {
+			t.Errorf("%s: %v", c.desc, err)
+			continue
+		}
+		if d.IsRange() != (len(c.scripts) > 1) {
+			t.Errorf("%s: IsRange %v", c.desc, d.IsRange())
+		}
+		for i, want := range c.scripts {
+			if s, err := d.Script(uint32(i)); err != nil || hex.EncodeToString(s) != want {
+				t.Errorf("%s: script %d %x, %v, want %s", c.desc, i, s, err, want)
+			}
+		}
+	}
+	for _, s := range []string{
+		"wpkh(" + wifU + ")",                     // uncompressed key in segwit
+		"pkh(" + xpF + "/1/*')",                  // hardened derivation from xpub
+		"pkh([deadbeef/1/2'/3/4']" + wif + "/0)", // path after WIF
+		"pkh([deadbee/1]" + wif + ")",            // short fingerprint
+	} {
+		if _, err := ParseDescriptor(s); err == nil {
+			t.Errorf("%s: parsed", s)
+		}
+	}
+	d, _ := ParseDescriptor("multi(1," + wif + "," + wifU + ")")
+	if a, err := d.Addresses(BTCMain, 0, 10); err != UnsupAddrType {
+		t.Errorf("addresses of bare multi(): %v, %v", a, err)
+	}
+}
+
+func TestDescriptorInvalid(t *testing.T) {
+	for _, s := range []string{
+		"multi_a(1," + gX + ")",
+		"wsh(multi_a(1," + gX + "))",
+		"tr(" + tapKey + ",multi(1,02" + gX + "))",
+		"tr(" + tapKey + ",pkh(" + gX + "))",
+		"wpkh(04" + gX + gY + ")",
+		"sh(sh(pkh(02" + gX + ")))",
+		"tr(" + tapKey + ",{pk(" + gX + ")})",
+	} {
+		if _, err := ParseDescriptor(s); err == nil {
+			t.Errorf("%s: parsed", s)
+		}
+	}
+	for _, s := range []string{
+		"tr(" + tapKey + ",multi_a(1," + gX + "))",
+		"tr(" + tapKey + ",{pk(" + gX + "),sortedmulti_a(1," + gX + "," + g2X + ")})",
+	} {
+		if _, err := ParseDescriptor(s); err != UnsupDescriptor {
+			t.Errorf("%s: %v", s, err)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package cckat
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+var (
+	InvSigF    = errors.New("invalid signature format")
+	InvHash    = errors.New("invalid hash length")
+	InvRecID   = errors.New("invalid recovery id")
+	RecoverErr = errors.New("public key cannot be recovered from the signature")
+)
+
+// (n-1)/2, the greatest low S value (BIP62/BIP146)
+var halfN = new(big.Int).Rsh(secp256k1.N, 1)
+
+// Signature is an ECDSA signature.
+type Signature struct {
+	R, S *big.Int
+}
+
+// Sign returns the ECDSA signature of hash. The nonce is generated deterministically (RFC 6979, HMAC-SHA256)
+// and S is normalized to the lower half of the order (BIP62/BIP146).
+// hash must be 32 bytes long (the output of SHA256, Keccak256 etc.).
+func (k *PrKey) Sign(hash []byte) (*Signature, error) {
+	if err := k.isSet(); err != nil {
+		return nil, err
+	}
+	if len(hash) != 32 {
+		return nil, InvHash
+	}
+	sig, _, err := signECDSA(&k.k, hash)
+	return sig, err
+}
+
+// SignRecoverable returns the ECDSA signature of hash (see Sign) and the recovery id (0 - 3)
+// which allows to recover the public key from the signature (see RecoverPubKey).
+func (k *PrKey) SignRecoverable(hash []byte) (*Signature, byte, error) {
+	if err := k.isSet(); err != nil {
+		return nil, 0, err
+	}
+	if len(hash) != 32 {
+		return nil, 0, InvHash
+	}
+	return signECDSA(&k.k, hash)
+}
+
+// signECDSA returns the low S signature of hash and the recovery id:
+// bit 0 is the parity of Y of R, bit 1 is set if X of R is not less than N.
+func signECDSA(d *big.Int, hash []byte) (*Signature, byte, error) {
+	z := hashToInt(hash)
+	nonce := rfc6979(d, hash)
+	for {
+		k := nonce()
+		rx, ry := secp256k1.ScalarBaseMult(k.Bytes())
+		var recid byte
+		if ry.Bit(0) == 1 {
+			recid = 1
+		}
+		if rx.Cmp(secp256k1.N) >= 0 {
+			recid |= 2
+		}
+		r := rx.Mod(rx, secp256k1.N)
+		if r.Sign() == 0 {
+			continue
+		}
+		// s = (z + r*d) / k in constant time
+		ks, rs, ds, zs := fn.fromBig(k), fn.fromBig(r), fn.fromBig(d), fn.fromBig(z)
+		ki := fn.inv(&ks)
+		t := fn.mul(&rs, &ds)
+		t = fn.add(&t, &zs)
+		t = fn.mul(&t, &ki)
+		s := fn.toBig(&t)
+		if s.Sign() == 0 {
+			continue
+		}
+		if s.Cmp(halfN) > 0 {
+			s.Sub(secp256k1.N, s)
+			recid ^= 1
+		}
+		return &Signature{R: r, S: s}, recid, nil
+	}
+}
+
+// rfc6979 returns the generator of the deterministic nonces for the private key d and hash.
+// See https://datatracker.ietf.org/doc/html/rfc6979#section-3.2
+func rfc6979(d *big.Int, hash []byte) func() *big.Int {
+	x := bytesFull(d)
+	h := bytesFull(new(big.Int).Mod(hashToInt(hash), secp256k1.N))
+	v := make([]byte, 32)
+	for i := range v {
+		v[i] = 0x01
+	}
+	kk := make([]byte, 32)
+	mac := func(data ...[]byte) []byte {
+		m := hmac.New(sha256.New, kk)
+		for _, d := range data {
+			m.Write(d)
+		}
+		return m.Sum(nil)
+	}
+	kk = mac(v, []byte{0x00}, x, h)
+	v = mac(v)
+	kk = mac(v, []byte{0x01}, x, h)
+	v = mac(v)
+	return func() *big.Int {
+		for {
+			v = mac(v)
+			k := new(big.Int).SetBytes(v)
+			kk = mac(v, []byte{0x00})
+			v = mac(v)
+			if k.Sign() > 0 && k.Cmp(secp256k1.N) < 0 {
+				return k
+			}
+		}
+	}
+}
+
+// hashToInt converts the hash to an integer (the leftmost 256 bits are used).
+func hashToInt(hash []byte) *big.Int {
+	if len(hash) > secp256k1.BitSize/8 {
+		hash = hash[:secp256k1.BitSize/8]
+	}
+	return new(big.Int).SetBytes(hash)
+}
+
+// Verify returns true if sig is a valid ECDSA signature of hash for the public key p.
+// Both low and high S values are accepted, use Signature.IsLowS to check.
+func (p PublicKey) Verify(hash []byte, sig *Signature) bool {
+	if !p.IsValid() || sig == nil || sig.R == nil || sig.S == nil {
+		return false
+	}
+	if sig.R.Sign() <= 0 || sig.S.Sign() <= 0 || sig.R.Cmp(secp256k1.N) >= 0 || sig.S.Cmp(secp256k1.N) >= 0 {
+		return false
+	}
+	w := new(big.Int).ModInverse(sig.S, secp256k1.N)
+	u1 := hashToInt(hash)
+	u1.Mul(u1, w)
+	u1.Mod(u1, secp256k1.N)
+	u2 := w.Mul(sig.R, w)
+	u2.Mod(u2, secp256k1.N)
+	qx, qy := p.Point()
+	x, y := secp256k1.CombinedMult(qx, qy, u1.Bytes(), u2.Bytes())
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return false
+	}
+	return x.Mod(x, secp256k1.N).Cmp(sig.R) == 0
+}
+
+// VerifySignature returns true if sig is a valid ECDSA signature of hash for pubKey (any format accepted by ParsePubKey).
+func VerifySignature(pubKey, hash []byte, sig *Signature) bool {
+	p, err := ParsePubKey(pubKey)
+	if err != nil {
+		return false
+	}
+	return p.Verify(hash, sig)
+}
+
+// RecoverPubKey returns the public key from the signature sig of hash and the recovery id recid (0 - 3).
+// Returns InvRecID if recid is out of range, RecoverErr if there is no such public key.
+func RecoverPubKey(hash []byte, sig *Signature, recid byte) (PublicKey, error) {
+	if recid > 3 {
+		return nil, InvRecID
+	}
+	if sig == nil || sig.R == nil || sig.S == nil || !sig.inRange() {
+		return nil, InvSigF
+	}
+	x := new(big.Int).Set(sig.R)
+	if recid&2 != 0 {
+		x.Add(x, secp256k1.N)
+		if x.Cmp(secp256k1.P) >= 0 {
+			return nil, RecoverErr
+		}
+	}
+	rx, ry, err := PointFromXc(bytesFull(x), recid&1 == 0)
+	if err != nil {
+		return nil, RecoverErr
+	}
+	// Q = r^-1 * (s*R - e*G)
+	ri := new(big.Int).ModInverse(sig.R, secp256k1.N)
+	u1 := new(big.Int).Mod(hashToInt(hash), secp256k1.N)
+	u1.Sub(secp256k1.N, u1)
+	u1.Mul(u1, ri)
+	u1.Mod(u1, secp256k1.N)
+	u2 := ri.Mul(ri, sig.S)
+	u2.Mod(u2, secp256k1.N)
+	qx, qy := secp256k1.CombinedMult(rx, ry, u1.Bytes(), u2.Bytes())
+	if qx.Sign() == 0 && qy.Sign() == 0 {
+		return nil, RecoverErr
+	}
+	return NewPublicKey(qx, qy)
+}
+
+// IsLowS returns true if S is not greater than (n-1)/2 (BIP62/BIP146).
+func (sig *Signature) IsLowS() bool {
+	return sig.S.Cmp(halfN) <= 0
+}
+
+// Normalize sets S to n-S if S is greater than (n-1)/2 and returns sig.
+func (sig *Signature) Normalize() *Signature {
+	if !sig.IsLowS() {
+		sig.S = new(big.Int).Sub(secp256k1.N, sig.S)
+	}
+	return sig
+}
+
+// Compact returns the 64 bytes R || S encoding of sig.
+func (sig *Signature) Compact() []byte {
+	return append(bytesFull(sig.R), bytesFull(sig.S)...)
+}
+
+// DER returns the strict DER (BIP66) encoding of sig.
+func (sig *Signature) DER() []byte {
+	r := derInt(sig.R)
+	s := derInt(sig.S)
+	res := make([]byte, 0, 6+len(r)+len(s))
+	res = append(res, 0x30, byte(4+len(r)+len(s)), 0x02, byte(len(r)))
+	res = append(res, r...)
+	res = append(res, 0x02, byte(len(s)))
+	return append(res, s...)
+}
+
+// derInt returns the minimal big-endian encoding of the positive integer i.
+func derInt(i *big.Int) []byte {
+	b := i.Bytes()
+	if len(b) == 0 || b[0]&0x80 != 0 {
+		b = append([]byte{0x00}, b...)
+	}
+	return b
+}
+
+// ParseCompact parses the 64 bytes R || S signature.
+// Returns nil and InvSigF if the length is wrong or R, S are out of range.
+func ParseCompact(b []byte) (*Signature, error) {
+	if len(b) != 64 {
+		return nil, InvSigF
+	}
+	sig := &Signature{R: new(big.Int).SetBytes(b[:32]), S: new(big.Int).SetBytes(b[32:])}
+	if !sig.inRange() {
+		return nil, InvSigF
+	}
+	return sig, nil
+}
+
+// ParseDER parses the strict DER (BIP66) encoded signature.
+// Returns nil and InvSigF if the encoding is not strict DER or R, S are out of range.
+func ParseDER(b []byte) (*Signature, error) {
+	if len(b) < 8 || len(b) > 72 || b[0] != 0x30 || int(b[1]) != len(b)-2 {
+		return nil, InvSigF
+	}
+	r, rest, ok := parseDERInt(b[2:])
+	if !ok {
+		return nil, InvSigF
+	}
+	s, rest, ok := parseDERInt(rest)
+	if !ok || len(rest) != 0 {
+		return nil, InvSigF
+	}
+	sig := &Signature{R: r, S: s}
+	if !sig.inRange() {
+		return nil, InvSigF
+	}
+	return sig, nil
+}
+
+// parseDERInt parses a minimally encoded positive DER integer from the start of b.
+func parseDERInt(b []byte) (*big.Int, []byte, bool) {
+	if len(b) < 3 || b[0] != 0x02 {
+		return nil, nil, false
+	}
+	l := int(b[1])
+	if l == 0 || l > 33 || len(b) < 2+l {
+		return nil, nil, false
+	}
+	v := b[2 : 2+l]
+	if v[0]&0x80 != 0 || (l > 1 && v[0] == 0x00 && v[1]&0x80 == 0) {
+		return nil, nil, false
+	}
+	return new(big.Int).SetBytes(v), b[2+l:], true
+}
+
+func (sig *Signature) inRange() bool {
+	return sig.R.Sign() > 0 && sig.S.Sign() > 0 && sig.R.Cmp(secp256k1.N) < 0 && sig.S.Cmp(secp256k1.N) < 0
+}
@@ -0,0 +1,206 @@
+package cckat
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+// RFC 6979 secp256k1 vectors (SHA256 of the message, the low S DER signature).
+var rfc6979Tests = []struct {
+	key, msg, nonce, sig string
+}{
+	{
+		"0000000000000000000000000000000000000000000000000000000000000001",
+		"Satoshi Nakamoto",
+		"8f8a276c19f4149656b280621e358cce24f5f52542772691ee69063b74f15d15",
+		"3045022100934b1ea10a4b3c1757e2b0c017d0b6143ce3c9a7e6a4a49860d7a6ab210ee3d802202442ce9d2b916064108014783e923ec36b49743e2ffa1c4496f01a512aafd9e5",
+	},
+	{
+		"fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364140",
+		"Satoshi Nakamoto",
+		"33a19b60e25fb6f4435af53a3d42d493644827367e6453928554f43e49aa6f90",
+		"3045022100fd567d121db66e382991534ada77a6bd3106f0a1098c231e47993447cd6af2d002206b39cd0eb1bc8603e159ef5c20a5c8ad685a45b06ce9bebed3f153d10d93bed5",
+	},
+	{
+		"f8b8af8ce3c7cca5e300d33939540c10d45ce001b8f252bfbc57ba0342904181",
+		"Alan Turing",
+		"525a82b70e67874398067543fd84c83d30c175fdc45fdeee082fe13b1d7cfdf1",
+		"304402207063ae83e7f62bbb171798131b4a0564b956930092b33b07b395615d9ec7e15c022058dfcc1e00a35e1572f366ffe34ba0fc47db1e7189759b9fb233c5b05ab388ea",
+	},
+	{
+		"0000000000000000000000000000000000000000000000000000000000000001",
+		"All those moments will be lost in time, like tears in rain. Time to die...",
+		"38aa22d72376b4dbc472e06c3ba403ee0a394da63fc58d88686c611aba98d6b3",
+		"30450221008600dbd41e348fe5c9465ab92d23e3db8b98b873beecd930736488696438cb6b0220547fe64427496db33bf66019dacbf0039c04199abb0122918601db38a72cfc21",
+	},
+	{
+		"e91671c46231f833a6406ccbea0e3e392c76c167bac1cb013f6f1013980455c2",
+		"There is a computer disease that anybody who works with computers knows about. It's a very serious disease and it interferes completely with the work. The trouble with computers is that you 'play' with them!",
+		"1f4b84c23a86a221d233f2521be018d9318639d5b8bbd6374a8a59232d16ad3d",
+		"3045022100b552edd27580141f3b2a5463048cb7cd3e047b97c9f98076c32dbdf85a68718b0220279fa72dd19bfae05577e06c7c0c1900c371fcd5893f7e1d56a37d30174671f6",
+	},
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func testKey(t *testing.T, h string) *PrKey {
+	t.Helper()
+	k, err := new(PrKey).Set(*new(big.Int).SetBytes(mustHex(t, h)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestSignRFC6979(t *testing.T) {
+	for _, c := range rfc6979Tests {
+		k := testKey(t, c.key)
+		hash := sha256.Sum256([]byte(c.msg))
+		if nonce := rfc6979(&k.k, hash[:])(); hex.EncodeToString(bytesFull(nonce)) != c.nonce {
+			t.Errorf("%s: nonce %x, want %s", c.msg, bytesFull(nonce), c.nonce)
+		}
+		sig, err := k.Sign(hash[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hex.EncodeToString(sig.DER()); got != c.sig {
+			t.Errorf("%s: signature %s, want %s", c.msg, got, c.sig)
+		}
+		if !sig.IsLowS() {
+			t.Errorf("%s: high S", c.msg)
+		}
+		if !k.PubK().Verify(hash[:], sig) {
+			t.Errorf("%s: not verified", c.msg)
+		}
+		hash[0] ^= 1
+		if k.PubK().Verify(hash[:], sig) {
+			t.Errorf("%s: verified for another hash", c.msg)
+		}
+	}
+}
+
+func TestSignatureEncoding(t *testing.T) {
+	for _, c := range rfc6979Tests {
+		der := mustHex(t, c.sig)
+		sig, err := ParseDER(der)
+		if err != nil {
+			t.Fatalf("%s: %v", c.sig, err)
+		}
+		if hex.EncodeToString(sig.DER()) != c.sig {
+			t.Errorf("DER round trip: %x", sig.DER())
+		}
+		sc, err := ParseCompact(sig.Compact())
+		if err != nil || sc.R.Cmp(sig.R) != 0 || sc.S.Cmp(sig.S) != 0 {
+			t.Errorf("compact round trip: %x, %v", sig.Compact(), err)
+		}
+	}
+	sig, _ := ParseDER(mustHex(t, rfc6979Tests[2].sig)) // R and S without the leading zero
+	der := sig.DER()
+	for name, b := range map[string][]byte{
+		"empty":         nil,
+		"wrong tag":     append([]byte{0x31}, der[1:]...),
+		"wrong length":  append([]byte{0x30, der[1] + 1}, der[2:]...),
+		"trailing byte": append(append([]byte{0x30, der[1] + 1}, der[2:]...), 0x00),
+		"negative R":    append(append(der[:4:4], 0x80), der[5:]...),
+		"padded R":      append([]byte{0x30, der[1] + 1, 0x02, der[3] + 1, 0x00}, der[4:]...),
+	} {
+		if _, err := ParseDER(b); err != InvSigF {
+			t.Errorf("DER %s: %v", name, err)
+		}
+	}
+	for name, b := range map[string][]byte{
+		"short":   sig.Compact()[:63],
+		"S zero":  append(bytesFull(sig.R), make([]byte, 32)...),
+		"R N":     append(bytesFull(secp256k1.N), bytesFull(sig.S)...),
+		"S N + 1": append(bytesFull(sig.R), bytesFull(new(big.Int).Add(secp256k1.N, big.NewInt(1)))...),
+	} {
+		if _, err := ParseCompact(b); err != InvSigF {
+			t.Errorf("compact %s: %v", name, err)
+		}
+	}
+}
+
+func TestHighS(t *testing.T) {
+	c := rfc6979Tests[0]
+	k := testKey(t, c.key)
+	hash := sha256.Sum256([]byte(c.msg))
+	sig, _ := k.Sign(hash[:])
+	high := &Signature{R: sig.R, S: new(big.Int).Sub(secp256k1.N, sig.S)}
+	if high.IsLowS() {
+		t.Fatal("IsLowS accepts the high S signature")
+	}
+	if !k.PubK().Verify(hash[:], high) {
+		t.Error("the high S signature is not mathematically valid")
+	}
+	if high.Normalize(); high.S.Cmp(sig.S) != 0 || !high.IsLowS() {
+		t.Errorf("Normalize: %x", high.S)
+	}
+	if s := (&Signature{R: sig.R, S: new(big.Int).Set(halfN)}); !s.IsLowS() {
+		t.Error("IsLowS rejects (n-1)/2")
+	}
+	if s := (&Signature{R: sig.R, S: new(big.Int).Add(halfN, big.NewInt(1))}); s.IsLowS() {
+		t.Error("IsLowS accepts (n+1)/2")
+	}
+}
+
+func TestRecoverPubKey(t *testing.T) {
+	for _, c := range rfc6979Tests {
+		k := testKey(t, c.key)
+		hash := sha256.Sum256([]byte(c.msg))
+		sig, recid, err := k.SignRecoverable(hash[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		for id := byte(0); id < 4; id++ {
+			p, err := RecoverPubKey(hash[:], sig, id)
+			switch {
+			case id == recid && (err != nil || string(p) != string(k.PubK())):
+				t.Errorf("%s: recid %d: %x, %v", c.msg, id, []byte(p), err)
+			case id != recid && err == nil && string(p) == string(k.PubK()):
+				t.Errorf("%s: the key is recovered with recid %d, want %d", c.msg, id, recid)
+			case err == nil && !p.Verify(hash[:], sig):
+				t.Errorf("%s: the key recovered with recid %d does not verify", c.msg, id)
+			}
+		}
+	}
+
+	// R with X >= N (recovery ids 2 and 3): r = X - N, any S and hash
+	x := new(big.Int).Add(secp256k1.N, big.NewInt(1))
+	for {
+		if _, _, err := PointFromXc(bytesFull(x), true); err == nil {
+			break
+		}
+		x.Add(x, big.NewInt(1))
+	}
+	hash := sha256.Sum256([]byte("recovery id"))
+	sig := &Signature{R: new(big.Int).Sub(x, secp256k1.N), S: big.NewInt(12345)}
+	var keys [4]PublicKey
+	for id := byte(0); id < 4; id++ {
+		p, err := RecoverPubKey(hash[:], sig, id)
+		if err != nil {
+			if id >= 2 {
+				t.Fatalf("recid %d: %v", id, err)
+			}
+			continue
+		}
+		if !p.Verify(hash[:], sig) {
+			t.Errorf("recid %d: the recovered key does not verify", id)
+		}
+		keys[id] = p
+	}
+	if string(keys[2]) == string(keys[3]) {
+		t.Error("recid 2 and 3 recover the same key")
+	}
+	if _, err := RecoverPubKey(hash[:], sig, 4); err != InvRecID {
+		t.Errorf("recid 4: %v", err)
+	}
+}
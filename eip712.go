@@ -0,0 +1,335 @@
+package cckat
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	InvTypedData  = errors.New("invalid EIP-712 typed data")
+	InvTypedType  = errors.New("invalid or undefined EIP-712 type")
+	InvTypedValue = errors.New("invalid EIP-712 value")
+)
+
+// TypedData is the EIP-712 typed structured data in the JSON format of eth_signTypedData_v4.
+// See https://eips.ethereum.org/EIPS/eip-712
+type TypedData struct {
+	Types       map[string][]TypedField `json:"types"`
+	PrimaryType string                  `json:"primaryType"`
+	Domain      map[string]any          `json:"domain"`
+	Message     map[string]any          `json:"message"`
+}
+
+// TypedField is a member of an EIP-712 struct type.
+type TypedField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+var reIntType = regexp.MustCompile(`^u?int([0-9]*)$`)
+var reBytesType = regexp.MustCompile(`^bytes([0-9]+)$`)
+
+// ParseTypedData parses the JSON typed data document b.
+// The numbers are kept as json.Number, so integers up to 256 bits are not rounded.
+func ParseTypedData(b []byte) (*TypedData, error) {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	td := new(TypedData)
+	if err := d.Decode(td); err != nil {
+		return nil, InvTypedData
+	}
+	if td.Types == nil || td.PrimaryType == "" {
+		return nil, InvTypedData
+	}
+	if _, ok := td.Types[td.PrimaryType]; !ok {
+		return nil, InvTypedType
+	}
+	return td, nil
+}
+
+// baseType returns the type t without the array suffixes.
+func baseType(t string) string {
+	if i := strings.IndexByte(t, '['); i >= 0 {
+		return t[:i]
+	}
+	return t
+}
+
+// dependencies adds the struct types which t depends on (including t) to deps.
+func (td *TypedData) dependencies(t string, deps map[string]bool) {
+	t = baseType(t)
+	if deps[t] {
+		return
+	}
+	fields, ok := td.Types[t]
+	if !ok {
+		return
+	}
+	deps[t] = true
+	for _, f := range fields {
+		td.dependencies(f.Type, deps)
+	}
+}
+
+// EncodeType returns the encoding of the struct type t like "Mail(Person from,Person to,string contents)Person(string name,address wallet)".
+func (td *TypedData) EncodeType(t string) (string, error) {
+	if _, ok := td.Types[t]; !ok {
+		return "", InvTypedType
+	}
+	deps := map[string]bool{}
+	td.dependencies(t, deps)
+	delete(deps, t)
+	names := make([]string, 0, len(deps)+1)
+	for d := range deps {
+		names = append(names, d)
+	}
+	slices.Sort(names)
+	var b strings.Builder
+	for _, n := range append([]string{t}, names...) {
+		b.WriteString(n)
+		b.WriteString("(")
+		for i, f := range td.Types[n] {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			b.WriteString(f.Type)
+			b.WriteString(" ")
+			b.WriteString(f.Name)
+		}
+		b.WriteString(")")
+	}
+	return b.String(), nil
+}
+
+// TypeHash returns keccak256(EncodeType(t)).
+func (td *TypedData) TypeHash(t string) ([]byte, error) {
+	e, err := td.EncodeType(t)
+	if err != nil {
+		return nil, err
+	}
+	return Keccak256Hash([]byte(e)), nil
+}
+
+// HashStruct returns keccak256(typeHash || encodeData(data)) for the struct type t.
+// The missing members of data are encoded as zero values.
+func (td *TypedData) HashStruct(t string, data map[string]any) ([]byte, error) {
+	th, err := td.TypeHash(t)
+	if err != nil {
+		return nil, err
+	}
+	buf := th
+	for _, f := range td.Types[t] {
+		v, err := td.encodeValue(f.Type, data[f.Name])
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, v...)
+	}
+	return Keccak256Hash(buf), nil
+}
+
+// encodeValue returns the 32 bytes encoding of the value v of the type t.
+func (td *TypedData) encodeValue(t string, v any) ([]byte, error) {
+	if strings.HasSuffix(t, "]") {
+		i := strings.LastIndexByte(t, '[')
+		if i < 0 {
+			return nil, InvTypedType
+		}
+		var items []any
+		if v != nil {
+			var ok bool
+			if items, ok = v.([]any); !ok {
+				return nil, InvTypedValue
+			}
+		}
+		if n := t[i+1 : len(t)-1]; n != "" {
+			if l, err := strconv.Atoi(n); err != nil || l != len(items) {
+				return nil, InvTypedValue
+			}
+		}
+		var buf []byte
+		for _, it := range items {
+			e, err := td.encodeValue(t[:i], it)
+			if err != nil {
+				return nil, err
+			}
+			buf = append(buf, e...)
+		}
+		return Keccak256Hash(buf), nil
+	}
+	if _, ok := td.Types[t]; ok {
+		var m map[string]any
+		if v != nil {
+			var ok bool
+			if m, ok = v.(map[string]any); !ok {
+				return nil, InvTypedValue
+			}
+		}
+		return td.HashStruct(t, m)
+	}
+	switch t {
+	case "string":
+		s, ok := v.(string)
+		if !ok && v != nil {
+			return nil, InvTypedValue
+		}
+		return Keccak256Hash([]byte(s)), nil
+	case "bytes":
+		b, err := typedBytes(v)
+		if err != nil {
+			return nil, err
+		}
+		return Keccak256Hash(b), nil
+	case "bool":
+		b, ok := v.(bool)
+		if !ok && v != nil {
+			return nil, InvTypedValue
+		}
+		res := make([]byte, 32)
+		if b {
+			res[31] = 1
+		}
+		return res, nil
+	case "address":
+		b, err := typedBytes(v)
+		if err != nil || len(b) > 20 {
+			return nil, InvTypedValue
+		}
+		return append(make([]byte, 32-len(b)), b...), nil
+	}
+	if m := reBytesType.FindStringSubmatch(t); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		if n < 1 || n > 32 {
+			return nil, InvTypedType
+		}
+		b, err := typedBytes(v)
+		if err != nil || len(b) > n {
+			return nil, InvTypedValue
+		}
+		return append(b, make([]byte, 32-len(b))...), nil
+	}
+	if m := reIntType.FindStringSubmatch(t); m != nil {
+		bits := 256
+		if m[1] != "" {
+			bits, _ = strconv.Atoi(m[1])
+		}
+		if bits < 8 || bits > 256 || bits%8 != 0 {
+			return nil, InvTypedType
+		}
+		return typedInt(v, bits, t[0] != 'u')
+	}
+	return nil, InvTypedType
+}
+
+// typedBytes returns the bytes of the hex string v ("0x" prefixed) or of []byte v.
+func typedBytes(v any) ([]byte, error) {
+	switch b := v.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return b, nil
+	case string:
+		b = strings.TrimPrefix(strings.TrimPrefix(b, "0x"), "0X")
+		if len(b)%2 != 0 || (b != "" && !isHex(b)) {
+			return nil, InvTypedValue
+		}
+		return hex.DecodeString(b)
+	}
+	return nil, InvTypedValue
+}
+
+// typedInt returns the 32 bytes two's complement encoding of the integer v of bits size.
+// v may be json.Number, a decimal or "0x" prefixed hex string, *big.Int, int, int64, uint64 or float64 (integral).
+func typedInt(v any, bits int, signed bool) ([]byte, error) {
+	x := new(big.Int)
+	ok := true
+	switch n := v.(type) {
+	case nil:
+	case json.Number:
+		_, ok = x.SetString(n.String(), 10)
+	case string:
+		if strings.HasPrefix(n, "0x") || strings.HasPrefix(n, "0X") {
+			_, ok = x.SetString(n[2:], 16)
+		} else {
+			_, ok = x.SetString(n, 10)
+		}
+	case *big.Int:
+		x.Set(n)
+	case int:
+		x.SetInt64(int64(n))
+	case int64:
+		x.SetInt64(n)
+	case uint64:
+		x.SetUint64(n)
+	case float64:
+		ok = n == float64(int64(n))
+		x.SetInt64(int64(n))
+	default:
+		ok = false
+	}
+	if !ok {
+		return nil, InvTypedValue
+	}
+	lim := new(big.Int).Lsh(one, uint(bits))
+	if signed {
+		lim.Rsh(lim, 1)
+		if x.Cmp(lim) >= 0 || x.Cmp(new(big.Int).Neg(lim)) < 0 {
+			return nil, InvTypedValue
+		}
+	} else if x.Sign() < 0 || x.Cmp(lim) >= 0 {
+		return nil, InvTypedValue
+	}
+	if x.Sign() < 0 {
+		x.Add(x, new(big.Int).Lsh(one, 256))
+	}
+	return bytesFull(x), nil
+}
+
+// DomainSeparator returns hashStruct(EIP712Domain, td.Domain).
+func (td *TypedData) DomainSeparator() ([]byte, error) {
+	if _, ok := td.Types["EIP712Domain"]; !ok {
+		return nil, InvTypedType
+	}
+	return td.HashStruct("EIP712Domain", td.Domain)
+}
+
+// Digest returns the hash to be signed: keccak256("\x19\x01" || domainSeparator || hashStruct(message)).
+// The message hash is omitted if the primary type is EIP712Domain.
+func (td *TypedData) Digest() ([]byte, error) {
+	ds, err := td.DomainSeparator()
+	if err != nil {
+		return nil, err
+	}
+	buf := append([]byte{0x19, 0x01}, ds...)
+	if td.PrimaryType != "EIP712Domain" {
+		h, err := td.HashStruct(td.PrimaryType, td.Message)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, h...)
+	}
+	return Keccak256Hash(buf), nil
+}
+
+// SignTypedData returns the Ethereum signature r || s || v (v is 27 or 28) of the EIP-712 digest of td
+// (eth_signTypedData_v4).
+func (k *PrKey) SignTypedData(td *TypedData) ([]byte, error) {
+	h, err := td.Digest()
+	if err != nil {
+		return nil, err
+	}
+	return k.SignETH(h)
+}
+
+// VerifyTypedData returns true if sig is a valid signature of td by the address addr (see VerifyETH).
+func VerifyTypedData(addr string, td *TypedData, sig string) bool {
+	h, err := td.Digest()
+	return err == nil && VerifyETH(addr, h, sig)
+}
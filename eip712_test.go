@@ -0,0 +1,94 @@
+package cckat
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// The "Mail" example of EIP-712.
+const mailTypedData = `{
+  "types": {
+    "EIP712Domain": [
+      {"name": "name", "type": "string"},
+      {"name": "version", "type": "string"},
+      {"name": "chainId", "type": "uint256"},
+      {"name": "verifyingContract", "type": "address"}
+    ],
+    "Person": [
+      {"name": "name", "type": "string"},
+      {"name": "wallet", "type": "address"}
+    ],
+    "Mail": [
+      {"name": "from", "type": "Person"},
+      {"name": "to", "type": "Person"},
+      {"name": "contents", "type": "string"}
+    ]
+  },
+  "primaryType": "Mail",
+  "domain": {
+    "name": "Ether Mail",
+    "version": "1",
+    "chainId": 1,
+    "verifyingContract": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"
+  },
+  "message": {
+    "from": {"name": "Cow", "wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"},
+    "to": {"name": "Bob", "wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"},
+    "contents": "Hello, Bob!"
+  }
+}`
+
+func TestTypedDataMail(t *testing.T) {
+	td, err := ParseTypedData([]byte(mailTypedData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if et, _ := td.EncodeType("Mail"); et != "Mail(Person from,Person to,string contents)Person(string name,address wallet)" {
+		t.Errorf("EncodeType: %s", et)
+	}
+	th, _ := td.TypeHash("Mail")
+	ds, err := td.DomainSeparator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs, err := td.HashStruct("Mail", td.Message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := td.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		name string
+		got  []byte
+		want string
+	}{
+		{"type hash", th, "a0cedeb2dc280ba39b857546d74f5549c3a1d7bdc2dd96bf881f76108e23dac2"},
+		{"domain separator", ds, "f2cee375fa42b42143804025fc449deafd50cc031ca257e0b194a650a912090f"},
+		{"struct hash", hs, "c52c0ee5d84264471806290a3f2c4cecfc5490626bf912d01f240d7a274b371e"},
+		{"digest", d, "be609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2"},
+	} {
+		if hex.EncodeToString(c.got) != c.want {
+			t.Errorf("%s: got %x, want %s", c.name, c.got, c.want)
+		}
+	}
+
+	k, _ := new(PrKey).SetBytes(Keccak256Hash([]byte("cow")))
+	sig, err := k.SignTypedData(td)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "4355c47d63924e8a72e509b65029052eb6c299d53a04e167c5775fd466751c9d" +
+		"07299936d304c153f6443dfa05f40ff007d72911b6f72307f996231605b91562" + "1c"
+	if hex.EncodeToString(sig) != want {
+		t.Errorf("signature: got %x, want %s", sig, want)
+	}
+	if !VerifyTypedData("0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826", td, want) {
+		t.Error("VerifyTypedData: false")
+	}
+	td.Message["contents"] = "Hello, Alice!"
+	if VerifyTypedData("0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826", td, want) {
+		t.Error("VerifyTypedData: true for another message")
+	}
+}
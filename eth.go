@@ -0,0 +1,91 @@
+package cckat
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// EIP191Hash returns the Keccak256 hash of msg with the EIP-191 prefix
+// "\x19Ethereum Signed Message:\n" + len(msg) used by personal_sign and eth_sign.
+func EIP191Hash(msg []byte) []byte {
+	b := []byte("\x19Ethereum Signed Message:\n" + strconv.Itoa(len(msg)))
+	return Keccak256Hash(append(b, msg...))
+}
+
+// SignETH returns the 65 bytes Ethereum signature r || s || v of the 32 bytes hash, v is 27 or 28.
+func (k *PrKey) SignETH(hash []byte) ([]byte, error) {
+	sig, recid, err := k.SignRecoverable(hash)
+	if err != nil {
+		return nil, err
+	}
+	if recid > 1 {
+		// R.x >= N happens with negligible probability and cannot be encoded with v of 27/28.
+		return nil, SignFailed
+	}
+	return append(sig.Compact(), 27+recid), nil
+}
+
+// SignPersonal returns the Ethereum signature (see SignETH) of the message msg prefixed according to EIP-191
+// (personal_sign).
+func (k *PrKey) SignPersonal(msg []byte) ([]byte, error) {
+	return k.SignETH(EIP191Hash(msg))
+}
+
+// ParseSignatureETH parses the 65 bytes Ethereum signature r || s || v (hex, may be prefixed with "0x")
+// with v in {0, 1, 27, 28} and returns the signature and the recovery id (0 or 1).
+func ParseSignatureETH(sig string) (*Signature, byte, error) {
+	sig = strings.TrimSpace(sig)
+	if strings.HasPrefix(sig, "0x") || strings.HasPrefix(sig, "0X") {
+		sig = sig[2:]
+	}
+	if !isHex(sig) {
+		return nil, 0, InvHexStr
+	}
+	b, _ := hex.DecodeString(sig)
+	if len(b) != 65 {
+		return nil, 0, InvSigF
+	}
+	v := b[64]
+	if v >= 27 {
+		v -= 27
+	}
+	if v > 1 {
+		return nil, 0, InvRecID
+	}
+	s, err := ParseCompact(b[:64])
+	if err != nil {
+		return nil, 0, err
+	}
+	return s, v, nil
+}
+
+// RecoverPubKeyETH returns the public key from the Ethereum signature sig (see ParseSignatureETH) of hash (ecrecover).
+func RecoverPubKeyETH(hash []byte, sig string) (PublicKey, error) {
+	s, v, err := ParseSignatureETH(sig)
+	if err != nil {
+		return nil, err
+	}
+	return RecoverPubKey(hash, s, v)
+}
+
+// RecoverETH returns the Ethereum address (mixed-case checksum) of the signer of hash (ecrecover).
+func RecoverETH(hash []byte, sig string) (string, error) {
+	p, err := RecoverPubKeyETH(hash, sig)
+	if err != nil {
+		return "", err
+	}
+	return GetAddressETH(p)
+}
+
+// VerifyETH returns true if sig is a valid Ethereum signature of hash by the address addr
+// (the case of addr is ignored).
+func VerifyETH(addr string, hash []byte, sig string) bool {
+	a, err := RecoverETH(hash, sig)
+	return err == nil && strings.EqualFold(a, "0x"+strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(addr), "0x"), "0X"))
+}
+
+// VerifyPersonal returns true if sig is a valid personal_sign (EIP-191) signature of msg by the address addr.
+func VerifyPersonal(addr string, msg []byte, sig string) bool {
+	return VerifyETH(addr, EIP191Hash(msg), sig)
+}
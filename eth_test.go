@@ -0,0 +1,77 @@
+package cckat
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// personal_sign vectors: the key, the message, the signature and the address.
+// The first one is the example of the web3.js accounts.sign documentation, the second one (v = 27)
+// was produced with go-ethereum.
+var personalSignTests = []struct {
+	key, msg, sig, addr string
+}{
+	{"4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318", "Some data",
+		"b91467e570a6466aa9e9876cbcd013baba02900b8979d43fe208a4a4f339f5fd6007e74cd82e037b800186422fc2da167c747ef045e5d18a5f5d4300f8e1a0291c",
+		"0x2c7536E3605D9C16a7a3D7b1898e529396a65c23"},
+	{"def5955cf732bfbbe31fa5cf40b44d249c6f5661087a95d843e67d939d0d014d", "hello world 125",
+		"644f779f206f0342cb9a75875a8c3261a7d2ddf33f5e74a4dd2dd6ec35778ff1333300a14b96f1849d6dd32fdfca4fd30cfeeff4cb79c20abdd42269d74455471b",
+		"0xab8AF723bA8b4c5a408D66e59F6c05fE2Bf54847"},
+}
+
+func TestSignPersonal(t *testing.T) {
+	if h := hex.EncodeToString(EIP191Hash([]byte("Some data"))); h != "1da44b586eb0729ff70a73c326926f6ed5a25f5b056e7f47fbc6e58d86871655" {
+		t.Errorf("EIP191Hash: %s", h)
+	}
+	for _, c := range personalSignTests {
+		sig, err := testKey(t, c.key).SignPersonal([]byte(c.msg))
+		if err != nil || hex.EncodeToString(sig) != c.sig {
+			t.Errorf("%s: got %x, %v, want %s", c.msg, sig, err, c.sig)
+		}
+		if a, err := RecoverETH(EIP191Hash([]byte(c.msg)), "0x"+c.sig); err != nil || a != c.addr {
+			t.Errorf("%s: RecoverETH %s, %v", c.msg, a, err)
+		}
+		for _, addr := range []string{c.addr, strings.ToLower(c.addr), strings.TrimPrefix(c.addr, "0x")} {
+			if !VerifyPersonal(addr, []byte(c.msg), c.sig) {
+				t.Errorf("%s: VerifyPersonal %s failed", c.msg, addr)
+			}
+		}
+		if VerifyPersonal(c.addr, []byte(c.msg+"."), c.sig) {
+			t.Errorf("%s: verified a different message", c.msg)
+		}
+		if VerifyPersonal(personalSignTests[0].addr, []byte(personalSignTests[1].msg), personalSignTests[1].sig) {
+			t.Errorf("%s: verified a different address", c.msg)
+		}
+	}
+}
+
+func TestParseSignatureETH(t *testing.T) {
+	c := personalSignTests[0]
+	hash := EIP191Hash([]byte(c.msg))
+	for v, want := range map[byte]byte{0: 0, 1: 1, 27: 0, 28: 1} {
+		sig := c.sig[:128] + hex.EncodeToString([]byte{v})
+		_, recid, err := ParseSignatureETH(sig)
+		if err != nil || recid != want {
+			t.Errorf("v %d: recid %d, %v", v, recid, err)
+		}
+		// the signature of the vector has v = 28
+		if a, err := RecoverETH(hash, sig); (err == nil && a == c.addr) != (want == 1) {
+			t.Errorf("v %d: RecoverETH %s, %v", v, a, err)
+		}
+	}
+	for _, v := range []byte{2, 3, 26, 29, 30, 35, 37, 255} {
+		if _, _, err := ParseSignatureETH(c.sig[:128] + hex.EncodeToString([]byte{v})); err != InvRecID {
+			t.Errorf("v %d: %v", v, err)
+		}
+	}
+	for name, s := range map[string]string{
+		"short":   c.sig[:128],
+		"long":    c.sig + "00",
+		"not hex": c.sig[:129] + "g",
+	} {
+		if _, _, err := ParseSignatureETH(s); err == nil {
+			t.Errorf("%s: parsed", name)
+		}
+	}
+}
@@ -0,0 +1,346 @@
+package cckat
+
+import (
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+// The Ethereum transaction types
+const (
+	LegacyTxType     byte = 0x00 // legacy with EIP-155 replay protection if ChainID is set
+	AccessListTxType byte = 0x01 // EIP-2930
+	DynamicFeeTxType byte = 0x02 // EIP-1559
+)
+
+var (
+	InvEthTx    = errors.New("invalid Ethereum transaction")
+	UnsupTxType = errors.New("unsupported Ethereum transaction type")
+	TxNotSigned = errors.New("transaction is not signed")
+)
+
+// EthTx is an Ethereum transaction of the type 0 (legacy), 1 (EIP-2930) or 2 (EIP-1559).
+// The nil integers are treated as 0.
+type EthTx struct {
+	Type       byte
+	ChainID    *big.Int // nil or 0 for legacy transactions without EIP-155
+	Nonce      uint64
+	GasPrice   *big.Int // types 0 and 1
+	GasTipCap  *big.Int // type 2, maxPriorityFeePerGas
+	GasFeeCap  *big.Int // type 2, maxFeePerGas
+	Gas        uint64
+	To         []byte // 20 bytes, nil for contract creation
+	Value      *big.Int
+	Data       []byte
+	AccessList []AccessTuple // types 1 and 2
+	V, R, S    *big.Int      // the signature, V is the y parity for the types 1 and 2
+}
+
+// AccessTuple is an element of the access list (EIP-2930).
+type AccessTuple struct {
+	Address     []byte   // 20 bytes
+	StorageKeys [][]byte // 32 bytes each
+}
+
+// fields returns the RLP list of the transaction fields without the signature.
+func (tx *EthTx) fields() ([]any, error) {
+	if len(tx.To) != 0 && len(tx.To) != 20 {
+		return nil, InvEthTx
+	}
+	to := tx.To
+	if to == nil {
+		to = []byte{}
+	}
+	data := tx.Data
+	if data == nil {
+		data = []byte{}
+	}
+	switch tx.Type {
+	case LegacyTxType:
+		return []any{tx.Nonce, tx.GasPrice, tx.Gas, to, tx.Value, data}, nil
+	case AccessListTxType, DynamicFeeTxType:
+		al := make([]any, len(tx.AccessList))
+		for i, t := range tx.AccessList {
+			if len(t.Address) != 20 {
+				return nil, InvEthTx
+			}
+			keys := make([]any, len(t.StorageKeys))
+			for j, k := range t.StorageKeys {
+				if len(k) != 32 {
+					return nil, InvEthTx
+				}
+				keys[j] = k
+			}
+			al[i] = []any{t.Address, keys}
+		}
+		if tx.Type == AccessListTxType {
+			return []any{tx.ChainID, tx.Nonce, tx.GasPrice, tx.Gas, to, tx.Value, data, al}, nil
+		}
+		return []any{tx.ChainID, tx.Nonce, tx.GasTipCap, tx.GasFeeCap, tx.Gas, to, tx.Value, data, al}, nil
+	}
+	return nil, UnsupTxType
+}
+
+// eip155 returns true if tx is a legacy transaction with the chain ID.
+func (tx *EthTx) eip155() bool {
+	return tx.Type == LegacyTxType && tx.ChainID != nil && tx.ChainID.Sign() > 0
+}
+
+// SigningHash returns the hash of tx to be signed.
+func (tx *EthTx) SigningHash() ([]byte, error) {
+	f, err := tx.fields()
+	if err != nil {
+		return nil, err
+	}
+	if tx.eip155() {
+		f = append(f, tx.ChainID, uint64(0), uint64(0))
+	}
+	b, err := RLPEncode(f)
+	if err != nil {
+		return nil, err
+	}
+	if tx.Type != LegacyTxType {
+		b = append([]byte{tx.Type}, b...)
+	}
+	return Keccak256Hash(b), nil
+}
+
+// SignEthTx signs tx with k: sets V, R and S of tx. Returns tx.
+func (k *PrKey) SignEthTx(tx *EthTx) (*EthTx, error) {
+	h, err := tx.SigningHash()
+	if err != nil {
+		return nil, err
+	}
+	sig, recid, err := k.SignRecoverable(h)
+	if err != nil {
+		return nil, err
+	}
+	if recid > 1 {
+		return nil, SignFailed
+	}
+	v := big.NewInt(int64(recid))
+	switch {
+	case tx.eip155():
+		v.Add(v, new(big.Int).Lsh(tx.ChainID, 1))
+		v.Add(v, big.NewInt(35))
+	case tx.Type == LegacyTxType:
+		v.Add(v, big.NewInt(27))
+	}
+	tx.V, tx.R, tx.S = v, sig.R, sig.S
+	return tx, nil
+}
+
+// recid returns the recovery id of the signature of tx.
+// Returns InvSigF unless 0 < R, S < n and S is low (EIP-2).
+func (tx *EthTx) recid() (byte, error) {
+	if tx.V == nil || tx.R == nil || tx.S == nil {
+		return 0, TxNotSigned
+	}
+	if sig := (&Signature{R: tx.R, S: tx.S}); !sig.inRange() || !sig.IsLowS() {
+		return 0, InvSigF
+	}
+	v := new(big.Int).Set(tx.V)
+	switch {
+	case tx.eip155():
+		v.Sub(v, new(big.Int).Lsh(tx.ChainID, 1))
+		v.Sub(v, big.NewInt(35))
+	case tx.Type == LegacyTxType:
+		v.Sub(v, big.NewInt(27))
+	}
+	if !v.IsUint64() || v.Uint64() > 1 {
+		return 0, InvRecID
+	}
+	return byte(v.Uint64()), nil
+}
+
+// Raw returns the encoding of the signed transaction tx (RLP for the legacy transactions,
+// type || RLP for the typed transactions). Returns TxNotSigned if tx is not signed and InvSigF if the signature
+// is out of range or has high S (EIP-2).
+func (tx *EthTx) Raw() ([]byte, error) {
+	if _, err := tx.recid(); err != nil {
+		return nil, err
+	}
+	f, err := tx.fields()
+	if err != nil {
+		return nil, err
+	}
+	b, err := RLPEncode(append(f, tx.V, tx.R, tx.S))
+	if err != nil {
+		return nil, err
+	}
+	if tx.Type != LegacyTxType {
+		b = append([]byte{tx.Type}, b...)
+	}
+	return b, nil
+}
+
+// RawHex returns Raw as "0x" prefixed hex string.
+func (tx *EthTx) RawHex() (string, error) {
+	b, err := tx.Raw()
+	if err != nil {
+		return "", err
+	}
+	return "0x" + hex.EncodeToString(b), nil
+}
+
+// Hash returns the hash of the signed transaction tx (the transaction ID).
+func (tx *EthTx) Hash() ([]byte, error) {
+	b, err := tx.Raw()
+	if err != nil {
+		return nil, err
+	}
+	return Keccak256Hash(b), nil
+}
+
+// SenderPubKey returns the public key of the signer of tx.
+func (tx *EthTx) SenderPubKey() (PublicKey, error) {
+	recid, err := tx.recid()
+	if err != nil {
+		return nil, err
+	}
+	h, err := tx.SigningHash()
+	if err != nil {
+		return nil, err
+	}
+	return RecoverPubKey(h, &Signature{R: tx.R, S: tx.S}, recid)
+}
+
+// Sender returns the Ethereum address (mixed-case checksum) of the signer of tx.
+func (tx *EthTx) Sender() (string, error) {
+	p, err := tx.SenderPubKey()
+	if err != nil {
+		return "", err
+	}
+	return GetAddressETH(p)
+}
+
+// ParseEthTx decodes the raw signed transaction b (see Raw).
+// The chain ID of the legacy transactions is derived from V (EIP-155).
+// The signatures with R, S out of range or high S (EIP-2) are rejected with InvSigF.
+func ParseEthTx(b []byte) (*EthTx, error) {
+	if len(b) == 0 {
+		return nil, InvEthTx
+	}
+	tx := new(EthTx)
+	if b[0] < 0x7f {
+		tx.Type = b[0]
+		b = b[1:]
+		if tx.Type != AccessListTxType && tx.Type != DynamicFeeTxType {
+			return nil, UnsupTxType
+		}
+	}
+	v, err := RLPDecode(b)
+	if err != nil {
+		return nil, err
+	}
+	f, ok := v.([]any)
+	if !ok {
+		return nil, InvEthTx
+	}
+	n := map[byte]int{LegacyTxType: 9, AccessListTxType: 11, DynamicFeeTxType: 12}[tx.Type]
+	if len(f) != n {
+		return nil, InvEthTx
+	}
+	bigs := func(dst ...**big.Int) bool {
+		for i, d := range dst {
+			if *d, ok = rlpBig(f[i]); !ok {
+				return false
+			}
+		}
+		f = f[len(dst):]
+		return true
+	}
+	uints := func(dst ...*uint64) bool {
+		for i, d := range dst {
+			if *d, ok = rlpUint(f[i]); !ok {
+				return false
+			}
+		}
+		f = f[len(dst):]
+		return true
+	}
+	switch tx.Type {
+	case LegacyTxType:
+		ok = uints(&tx.Nonce) && bigs(&tx.GasPrice) && uints(&tx.Gas)
+	case AccessListTxType:
+		ok = bigs(&tx.ChainID) && uints(&tx.Nonce) && bigs(&tx.GasPrice) && uints(&tx.Gas)
+	case DynamicFeeTxType:
+		ok = bigs(&tx.ChainID) && uints(&tx.Nonce) && bigs(&tx.GasTipCap, &tx.GasFeeCap) && uints(&tx.Gas)
+	}
+	if !ok {
+		return nil, InvEthTx
+	}
+	if tx.To, ok = rlpBytes(f[0]); !ok || (len(tx.To) != 0 && len(tx.To) != 20) {
+		return nil, InvEthTx
+	}
+	if len(tx.To) == 0 {
+		tx.To = nil
+	}
+	f = f[1:]
+	if !bigs(&tx.Value) {
+		return nil, InvEthTx
+	}
+	if tx.Data, ok = rlpBytes(f[0]); !ok {
+		return nil, InvEthTx
+	}
+	f = f[1:]
+	if tx.Type != LegacyTxType {
+		if tx.AccessList, ok = parseAccessList(f[0]); !ok {
+			return nil, InvEthTx
+		}
+		f = f[1:]
+	}
+	if !bigs(&tx.V, &tx.R, &tx.S) {
+		return nil, InvEthTx
+	}
+	if tx.Type == LegacyTxType && tx.V.Cmp(big.NewInt(35)) >= 0 {
+		tx.ChainID = new(big.Int).Sub(tx.V, big.NewInt(35))
+		tx.ChainID.Rsh(tx.ChainID, 1)
+	}
+	if _, err := tx.recid(); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// ParseEthTxHex decodes the hex encoded raw signed transaction s (may be prefixed with "0x").
+func ParseEthTxHex(s string) (*EthTx, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s = s[2:]
+	}
+	if !isHex(s) {
+		return nil, InvHexStr
+	}
+	b, _ := hex.DecodeString(s)
+	return ParseEthTx(b)
+}
+
+func parseAccessList(v any) ([]AccessTuple, bool) {
+	l, ok := v.([]any)
+	if !ok {
+		return nil, false
+	}
+	res := make([]AccessTuple, len(l))
+	for i, it := range l {
+		t, ok := it.([]any)
+		if !ok || len(t) != 2 {
+			return nil, false
+		}
+		if res[i].Address, ok = rlpBytes(t[0]); !ok || len(res[i].Address) != 20 {
+			return nil, false
+		}
+		keys, ok := t[1].([]any)
+		if !ok {
+			return nil, false
+		}
+		res[i].StorageKeys = make([][]byte, len(keys))
+		for j, k := range keys {
+			if res[i].StorageKeys[j], ok = rlpBytes(k); !ok || len(res[i].StorageKeys[j]) != 32 {
+				return nil, false
+			}
+		}
+	}
+	return res, true
+}
@@ -0,0 +1,189 @@
+package cckat
+
+import (
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestRLP(t *testing.T) {
+	lorem := "Lorem ipsum dolor sit amet, consectetur adipisicing elit"
+	for _, c := range []struct {
+		v   any
+		enc string
+	}{
+		{"dog", "83646f67"},
+		{[]any{"cat", "dog"}, "c88363617483646f67"},
+		{"", "80"},
+		{[]any{}, "c0"},
+		{uint64(0), "80"},
+		{[]byte{0x00}, "00"},
+		{uint64(15), "0f"},
+		{uint64(1024), "820400"},
+		{[]any{[]any{}, []any{[]any{}}, []any{[]any{}, []any{[]any{}}}}, "c7c0c1c0c3c0c1c0"},
+		{lorem, "b838" + hex.EncodeToString([]byte(lorem))},
+	} {
+		b, err := RLPEncode(c.v)
+		if err != nil || hex.EncodeToString(b) != c.enc {
+			t.Errorf("%v: got %x, %v, want %s", c.v, b, err, c.enc)
+			continue
+		}
+		v, err := RLPDecode(b)
+		if err != nil {
+			t.Errorf("%s: %v", c.enc, err)
+			continue
+		}
+		if r, _ := RLPEncode(v); hex.EncodeToString(r) != c.enc {
+			t.Errorf("%s: round trip %x", c.enc, r)
+		}
+	}
+}
+
+func TestRLPDecodeInvalid(t *testing.T) {
+	for name, s := range map[string]string{
+		"empty":                         "",
+		"short string in long form":     "b803646f67",
+		"short list in long form":       "f803c0c0c0",
+		"leading zero in length":        "b90038" + strings.Repeat("61", 56),
+		"single byte < 0x80 wrapped":    "817f",
+		"trailing bytes":                "83646f6700",
+		"trailing bytes after list":     "c0c0",
+		"string longer than input":      "83646f",
+		"list longer than input":        "c3646f",
+		"long length longer than input": "b9ffff61",
+		"item longer than list":         "c283646f67",
+	} {
+		if _, err := RLPDecode(mustHex(t, s)); err != InvRLP {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+// The example of EIP-155.
+const (
+	eip155Key     = "4646464646464646464646464646464646464646464646464646464646464646"
+	eip155SigHash = "daf5a779ae972f972197303d7b574746c7ef83eadac0f2791ad23db92e4c8e53"
+	eip155Raw     = "f86c098504a817c800825208943535353535353535353535353535353535353535880de0b6b3a76400008025a028ef61340bd939bc2195fe537567866003e1a15d3c71ff63e1590620aa636276a067cbe9d8997f761aecb703304b3800ccf555c9f3dc64214b297fb1966a3b6d83"
+	eip155Sender  = "0x9d8A62f656a8d1615C1294fd71e9CFb3E4855A4F"
+)
+
+func TestEthTxEIP155(t *testing.T) {
+	tx := &EthTx{
+		ChainID:  big.NewInt(1),
+		Nonce:    9,
+		GasPrice: big.NewInt(20e9),
+		Gas:      21000,
+		To:       mustHex(t, "3535353535353535353535353535353535353535"),
+		Value:    big.NewInt(1e18),
+	}
+	if h, err := tx.SigningHash(); err != nil || hex.EncodeToString(h) != eip155SigHash {
+		t.Errorf("signing hash %x, %v", h, err)
+	}
+	if _, err := tx.Raw(); err != TxNotSigned {
+		t.Errorf("unsigned: %v", err)
+	}
+	if _, err := testKey(t, eip155Key).SignEthTx(tx); err != nil {
+		t.Fatal(err)
+	}
+	if r, err := tx.RawHex(); err != nil || r != "0x"+eip155Raw {
+		t.Errorf("raw %s, %v", r, err)
+	}
+	p, err := ParseEthTxHex("0x" + eip155Raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ChainID.Int64() != 1 || p.Nonce != 9 || p.Gas != 21000 || p.Value.Cmp(tx.Value) != 0 || p.V.Int64() != 37 {
+		t.Errorf("parsed %+v", p)
+	}
+	if a, err := p.Sender(); err != nil || a != eip155Sender {
+		t.Errorf("sender %s, %v", a, err)
+	}
+}
+
+// The transactions signed with go-ethereum by the key keccak256("tx key"): the signing hash, the raw transaction
+// and the transaction hash.
+var ethTxTests = []struct {
+	name, sigHash, raw, hash string
+}{
+	{"legacy without EIP-155", "",
+		"f86c098504a817c800825208943535353535353535353535353535353535353535880de0b6b3a7640000801ca0d42795c6cf7a6cec7e8f0e0b17b87b4bc75eb96cbcf6c2717fb9a2a45da2d6b5a07580c677f1c020b10978bec27daac6cc5b4740c2bb80925cbe294a570e5ad07b",
+		""},
+	{"legacy EIP-155", "daf5a779ae972f972197303d7b574746c7ef83eadac0f2791ad23db92e4c8e53",
+		"f86c098504a817c800825208943535353535353535353535353535353535353535880de0b6b3a76400008026a01ae149346e93619a6d24176f92e01b3958fa59b8d53ce3bca89ae78e90fc4982a03667d4202b24fc46030076a9d63800403e97eab06b119afed75fc2bad7e67978",
+		"4ffc15b796bd5898bc44cf59fa751c6fcaef038d9dbc27da99171c4eea537c09"},
+	{"EIP-2930", "02c759876499e14cdb55a5c64a8c128dacb1e828a894fd04cc544a301e707414",
+		"01f90128050184b2d05e0082c350943535353535353535353535353535353535353535823039b864000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f202122232425262728292a2b2c2d2e2f303132333435363738393a3b3c3d3e3f404142434445464748494a4b4c4d4e4f505152535455565758595a5b5c5d5e5f60616263f85bf85994de0b295669a9fd93d5f28d9ec85e40f4cb697baef842a00000000000000000000000000000000000000000000000000000000000000003a0000000000000000000000000000000000000000000000000000000000000000780a01362c7d9495da939ab53c7b35161183c5e0d929b8375cbacb0cb449fd6d070c5a01b2576a570d9a4a3fdfedfd9959c74772ff0d59649a1af4559e8d78fd3b498e1",
+		"fa9f88d710d2ed9f9030018c981bd764e5258da703879e2f3aa73d10218173e5"},
+	{"EIP-1559 contract creation", "3a84537e3ba604e13744f586b896a4a8ffe8467df78be42d59e52d6c6bcd9522",
+		"02f9011b0182012c847735940085174876e800830f42408080b864000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f202122232425262728292a2b2c2d2e2f303132333435363738393a3b3c3d3e3f404142434445464748494a4b4c4d4e4f505152535455565758595a5b5c5d5e5f60616263f85bf85994de0b295669a9fd93d5f28d9ec85e40f4cb697baef842a00000000000000000000000000000000000000000000000000000000000000003a0000000000000000000000000000000000000000000000000000000000000000780a05af32e2b5da5465f787a4a8337524babdc40f546b721f7fce57cc6d5fbe71023a02d67d70ab562143b2630d97d64539c5080f52c56d43199366b17cc6e2ca2ca1a",
+		"9b1512d4df1f60be4104a619089289200eb2a7200f0b767ef70b0c0a357cd663"},
+	{"EIP-1559", "3e881dd0e226ccde2ee79a918d7eb9fe12ced5f28958ef6da8a7f6c3a79d8bf7",
+		"02f87081898080018252089435353535353535353535353535353535353535358d1000000000000000000000000080c001a0da69d7729ac1b2ad2da66c6ab9e198e446a130ed5fda59744c2c4016afa9aa1fa039b8bf3cddf2ffaf5c6256bd14c03101868869781d32a12e54d5c444c552a7c5",
+		"3b5bd279a775a80957f3ca2c171b2d70db5e646e50c19c9a0d765324d6220986"},
+}
+
+func TestEthTxVectors(t *testing.T) {
+	k := testKey(t, hex.EncodeToString(Keccak256Hash([]byte("tx key"))))
+	for _, c := range ethTxTests {
+		tx, err := ParseEthTx(mustHex(t, c.raw))
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if a, err := tx.Sender(); err != nil || a != "0x513a9d38C230a07a67ad3beF4eD234164bA12672" {
+			t.Errorf("%s: sender %s, %v", c.name, a, err)
+		}
+		if h, _ := tx.SigningHash(); c.sigHash != "" && hex.EncodeToString(h) != c.sigHash {
+			t.Errorf("%s: signing hash %x", c.name, h)
+		}
+		if h, _ := tx.Hash(); c.hash != "" && hex.EncodeToString(h) != c.hash {
+			t.Errorf("%s: hash %x", c.name, h)
+		}
+		tx.V, tx.R, tx.S = nil, nil, nil
+		if _, err := k.SignEthTx(tx); err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if r, _ := tx.Raw(); hex.EncodeToString(r) != c.raw {
+			t.Errorf("%s: signed %x", c.name, r)
+		}
+	}
+}
+
+func TestParseEthTxInvalid(t *testing.T) {
+	f, err := RLPDecode(mustHex(t, eip155Raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := new(big.Int).SetBytes(f.([]any)[8].([]byte))
+	mod := func(i int, v any) string {
+		l := append([]any(nil), f.([]any)...)
+		l[i] = v
+		b, _ := RLPEncode(l)
+		return hex.EncodeToString(b)
+	}
+	for _, c := range []struct {
+		name, raw string
+		err       error
+	}{
+		{"nonce with a leading zero", mod(0, []byte{0x00, 0x09}), InvEthTx},
+		{"value with a leading zero", mod(4, append([]byte{0x00}, big.NewInt(1e18).Bytes()...)), InvEthTx},
+		{"short to", mod(3, make([]byte, 19)), InvEthTx},
+		{"list field", mod(1, []any{}), InvEthTx},
+		{"missing field", func() string { b, _ := RLPEncode(f.([]any)[:8]); return hex.EncodeToString(b) }(), InvEthTx},
+		{"wrong v", mod(6, uint64(36)), InvRecID},
+		{"R = 0", mod(7, uint64(0)), InvSigF},
+		{"R = N", mod(7, secp256k1.N), InvSigF},
+		{"S = 0", mod(8, uint64(0)), InvSigF},
+		{"high S", mod(8, new(big.Int).Sub(secp256k1.N, s)), InvSigF},
+		{"trailing bytes", eip155Raw + "00", InvRLP},
+		{"unsupported type", "03" + eip155Raw, UnsupTxType},
+		{"typed legacy", "00" + eip155Raw, UnsupTxType},
+		{"empty", "", InvEthTx},
+	} {
+		if _, err := ParseEthTx(mustHex(t, c.raw)); err != c.err {
+			t.Errorf("%s: %v, want %v", c.name, err, c.err)
+		}
+	}
+}
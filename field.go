@@ -0,0 +1,257 @@
+package cckat
+
+import (
+	"math/big"
+	"math/bits"
+)
+
+// fel is an element of the field modulo P or of the scalar field modulo N, in Montgomery form (R = 2^256).
+// The limbs are little-endian, the value is always fully reduced.
+// All the operations (except the conversions from/to big.Int) are constant time.
+type fel [4]uint64
+
+// modulus holds the constants of the Montgomery arithmetic modulo m.
+type modulus struct {
+	m   fel    // the modulus
+	m0  uint64 // -m^-1 mod 2^64
+	r2  fel    // R^2 mod m
+	one fel    // R mod m (1 in Montgomery form)
+	e2  fel    // m-2, the exponent of the inversion
+}
+
+var (
+	fp = newModulus(secp256k1.P) // the field modulo P
+	fn = newModulus(secp256k1.N) // the scalar field modulo N
+)
+
+func newModulus(m *big.Int) *modulus {
+	md := &modulus{m: limbsOf(m)}
+	inv := md.m[0] // correct modulo 2^3 for odd m, every iteration doubles the precision
+	for i := 0; i < 5; i++ {
+		inv *= 2 - md.m[0]*inv
+	}
+	md.m0 = -inv
+	r := new(big.Int).Lsh(one, 256)
+	md.one = limbsOf(r.Mod(r, m))
+	r.Lsh(one, 512)
+	md.r2 = limbsOf(r.Mod(r, m))
+	md.e2 = limbsOf(r.Sub(m, big.NewInt(2)))
+	return md
+}
+
+// limbsOf returns the limbs of x (0 <= x < 2^256).
+func limbsOf(x *big.Int) (l fel) {
+	var b [32]byte
+	x.FillBytes(b[:])
+	return limbsFromBytes(&b)
+}
+
+func limbsFromBytes(b *[32]byte) (l fel) {
+	for i := range l {
+		for j := 0; j < 8; j++ {
+			l[i] |= uint64(b[31-i*8-j]) << (8 * j)
+		}
+	}
+	return
+}
+
+func (l *fel) bytes() (b [32]byte) {
+	for i := range l {
+		for j := 0; j < 8; j++ {
+			b[31-i*8-j] = byte(l[i] >> (8 * j))
+		}
+	}
+	return
+}
+
+// mulAdd returns a*b + c + d as hi, lo.
+func mulAdd(a, b, c, d uint64) (hi, lo uint64) {
+	hi, lo = bits.Mul64(a, b)
+	var cc uint64
+	lo, cc = bits.Add64(lo, c, 0)
+	hi += cc
+	lo, cc = bits.Add64(lo, d, 0)
+	hi += cc
+	return
+}
+
+// cmov sets a to b if c == 1 and leaves a unchanged if c == 0.
+func (a *fel) cmov(b *fel, c uint64) {
+	mask := -c
+	for i := range a {
+		a[i] ^= mask & (a[i] ^ b[i])
+	}
+}
+
+// isZero returns 1 if a == 0 and 0 otherwise.
+func (a *fel) isZero() uint64 {
+	x := a[0] | a[1] | a[2] | a[3]
+	return 1 ^ (x|-x)>>63
+}
+
+// equal returns 1 if a == b and 0 otherwise.
+func (a *fel) equal(b *fel) uint64 {
+	x := (a[0] ^ b[0]) | (a[1] ^ b[1]) | (a[2] ^ b[2]) | (a[3] ^ b[3])
+	return 1 ^ (x|-x)>>63
+}
+
+// reduce returns t - m if hi == 1 or t >= m and t otherwise (t < 2m).
+func (md *modulus) reduce(t fel, hi uint64) fel {
+	var d fel
+	var b uint64
+	d[0], b = bits.Sub64(t[0], md.m[0], 0)
+	d[1], b = bits.Sub64(t[1], md.m[1], b)
+	d[2], b = bits.Sub64(t[2], md.m[2], b)
+	d[3], b = bits.Sub64(t[3], md.m[3], b)
+	t.cmov(&d, hi|(b^1))
+	return t
+}
+
+// mul returns a*b*R^-1 mod m (the Montgomery product, CIOS method, unrolled).
+func (md *modulus) mul(a, b *fel) fel {
+	m0, m1, m2, m3 := md.m[0], md.m[1], md.m[2], md.m[3]
+	a0, a1, a2, a3 := a[0], a[1], a[2], a[3]
+	var t0, t1, t2, t3, t4, t5, c, m uint64
+	// b[0]
+	c, t0 = mulAdd(a0, b[0], t0, 0)
+	c, t1 = mulAdd(a1, b[0], t1, c)
+	c, t2 = mulAdd(a2, b[0], t2, c)
+	c, t3 = mulAdd(a3, b[0], t3, c)
+	t4, t5 = bits.Add64(t4, c, 0)
+	m = t0 * md.m0
+	c, _ = mulAdd(m, m0, t0, 0)
+	c, t0 = mulAdd(m, m1, t1, c)
+	c, t1 = mulAdd(m, m2, t2, c)
+	c, t2 = mulAdd(m, m3, t3, c)
+	t3, c = bits.Add64(t4, c, 0)
+	t4 = t5 + c
+	// b[1]
+	c, t0 = mulAdd(a0, b[1], t0, 0)
+	c, t1 = mulAdd(a1, b[1], t1, c)
+	c, t2 = mulAdd(a2, b[1], t2, c)
+	c, t3 = mulAdd(a3, b[1], t3, c)
+	t4, t5 = bits.Add64(t4, c, 0)
+	m = t0 * md.m0
+	c, _ = mulAdd(m, m0, t0, 0)
+	c, t0 = mulAdd(m, m1, t1, c)
+	c, t1 = mulAdd(m, m2, t2, c)
+	c, t2 = mulAdd(m, m3, t3, c)
+	t3, c = bits.Add64(t4, c, 0)
+	t4 = t5 + c
+	// b[2]
+	c, t0 = mulAdd(a0, b[2], t0, 0)
+	c, t1 = mulAdd(a1, b[2], t1, c)
+	c, t2 = mulAdd(a2, b[2], t2, c)
+	c, t3 = mulAdd(a3, b[2], t3, c)
+	t4, t5 = bits.Add64(t4, c, 0)
+	m = t0 * md.m0
+	c, _ = mulAdd(m, m0, t0, 0)
+	c, t0 = mulAdd(m, m1, t1, c)
+	c, t1 = mulAdd(m, m2, t2, c)
+	c, t2 = mulAdd(m, m3, t3, c)
+	t3, c = bits.Add64(t4, c, 0)
+	t4 = t5 + c
+	// b[3]
+	c, t0 = mulAdd(a0, b[3], t0, 0)
+	c, t1 = mulAdd(a1, b[3], t1, c)
+	c, t2 = mulAdd(a2, b[3], t2, c)
+	c, t3 = mulAdd(a3, b[3], t3, c)
+	t4, t5 = bits.Add64(t4, c, 0)
+	m = t0 * md.m0
+	c, _ = mulAdd(m, m0, t0, 0)
+	c, t0 = mulAdd(m, m1, t1, c)
+	c, t1 = mulAdd(m, m2, t2, c)
+	c, t2 = mulAdd(m, m3, t3, c)
+	t3, c = bits.Add64(t4, c, 0)
+	t4 = t5 + c
+	// reduce inlined
+	var d0, d1, d2, d3, bb uint64
+	d0, bb = bits.Sub64(t0, m0, 0)
+	d1, bb = bits.Sub64(t1, m1, bb)
+	d2, bb = bits.Sub64(t2, m2, bb)
+	d3, bb = bits.Sub64(t3, m3, bb)
+	mask := -(t4 | (bb ^ 1))
+	return fel{t0 ^ mask&(t0^d0), t1 ^ mask&(t1^d1), t2 ^ mask&(t2^d2), t3 ^ mask&(t3^d3)}
+}
+
+func (md *modulus) sqr(a *fel) fel {
+	return md.mul(a, a)
+}
+
+func (md *modulus) add(a, b *fel) fel {
+	var s fel
+	var c uint64
+	s[0], c = bits.Add64(a[0], b[0], 0)
+	s[1], c = bits.Add64(a[1], b[1], c)
+	s[2], c = bits.Add64(a[2], b[2], c)
+	s[3], c = bits.Add64(a[3], b[3], c)
+	return md.reduce(s, c)
+}
+
+func (md *modulus) sub(a, b *fel) fel {
+	var d fel
+	var c, bb uint64
+	d[0], bb = bits.Sub64(a[0], b[0], 0)
+	d[1], bb = bits.Sub64(a[1], b[1], bb)
+	d[2], bb = bits.Sub64(a[2], b[2], bb)
+	d[3], bb = bits.Sub64(a[3], b[3], bb)
+	mask := -bb
+	d[0], c = bits.Add64(d[0], md.m[0]&mask, 0)
+	d[1], c = bits.Add64(d[1], md.m[1]&mask, c)
+	d[2], c = bits.Add64(d[2], md.m[2]&mask, c)
+	d[3], _ = bits.Add64(d[3], md.m[3]&mask, c)
+	return d
+}
+
+func (md *modulus) neg(a *fel) fel {
+	return md.sub(&fel{}, a)
+}
+
+// exp returns a^e with the fixed 4-bit window (e is public, the time depends only on e).
+func (md *modulus) exp(a *fel, e *fel) fel {
+	var t [16]fel
+	t[0], t[1] = md.one, *a
+	for i := 2; i < 16; i++ {
+		t[i] = md.mul(&t[i-1], a)
+	}
+	r := md.one
+	for i := 63; i >= 0; i-- {
+		r = md.sqr(&r)
+		r = md.sqr(&r)
+		r = md.sqr(&r)
+		r = md.sqr(&r)
+		if w := e[i/16] >> (4 * (i % 16)) & 0x0f; w != 0 {
+			r = md.mul(&r, &t[w])
+		}
+	}
+	return r
+}
+
+// inv returns a^-1 (0 for a == 0).
+func (md *modulus) inv(a *fel) fel {
+	return md.exp(a, &md.e2)
+}
+
+// fromBytes returns b mod m in Montgomery form (b is big-endian, m > 2^255 so one subtraction is enough).
+func (md *modulus) fromBytes(b *[32]byte) fel {
+	l := md.reduce(limbsFromBytes(b), 0)
+	return md.mul(&l, &md.r2)
+}
+
+// toBytes returns a in normal form (big-endian).
+func (md *modulus) toBytes(a *fel) [32]byte {
+	l := md.mul(a, &fel{1})
+	return l.bytes()
+}
+
+// fromBig returns x mod m in Montgomery form (0 <= x < 2^256).
+func (md *modulus) fromBig(x *big.Int) fel {
+	var b [32]byte
+	x.FillBytes(b[:])
+	return md.fromBytes(&b)
+}
+
+func (md *modulus) toBig(a *fel) *big.Int {
+	b := md.toBytes(a)
+	return new(big.Int).SetBytes(b[:])
+}
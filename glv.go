@@ -0,0 +1,226 @@
+package cckat
+
+import (
+	"math/big"
+	"math/bits"
+	"sync"
+)
+
+// The secp256k1 endomorphism (GLV): lambda*(x, y) = (beta*x, y), lambda^3 = 1 mod N, beta^3 = 1 mod P.
+// A scalar k is split into k1 + k2*lambda with |k1|, |k2| < 2^128, so k*P = k1*P + k2*(beta*x, y)
+// needs only half of the doublings. See https://www.iacr.org/archive/crypto2001/21390189.pdf
+var (
+	glvBeta        = fp.fromBig(hexInt("7AE96A2B657C07106E64479EAC3434E99CF0497512F58995C1396C28719501EE"))
+	glvMinusLambda = fn.fromBig(hexInt("AC9C52B33FA3CF1F5AD9E3FD77ED9BA4A880B9FC8EC739C2E0CFC810B51283CF"))
+	glvMinusB1     = fn.fromBig(hexInt("E4437ED6010E88286F547FA90ABFE4C3"))
+	glvMinusB2     = fn.fromBig(hexInt("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFE8A280AC50774346DD765CDA83DB1562C"))
+	// round(2^384*b2/N) and round(2^384*(-b1)/N), normal form
+	glvG1 = limbsOf(hexInt("3086D221A7D46BCDE86C90E49284EB153DAA8A1471E8CA7FE893209A45DBB031"))
+	glvG2 = limbsOf(hexInt("E4437ED6010E88286F547FA90ABFE4C4221208AC9DF506C61571B4AE8AC47F71"))
+	// (N-1)/2, normal form
+	glvHalfN = limbsOf(halfN)
+)
+
+func hexInt(s string) *big.Int {
+	x, _ := new(big.Int).SetString(s, 16)
+	return x
+}
+
+// mulShift384 returns round(a*b / 2^384) for a, b in normal form (the result must be less than 2^128).
+func mulShift384(a, b *fel) fel {
+	var t [8]uint64
+	for i := 0; i < 4; i++ {
+		var c uint64
+		for j := 0; j < 4; j++ {
+			c, t[i+j] = mulAdd(a[j], b[i], t[i+j], c)
+		}
+		t[i+4] = c
+	}
+	var r fel
+	var c uint64
+	r[0], c = bits.Add64(t[6], t[5]>>63, 0)
+	r[1] = t[7] + c
+	return r
+}
+
+// abs returns min(a, N-a) for a in normal form and 1 if N-a was taken (a > (N-1)/2), constant time.
+func abs(a fel) (fel, uint64) {
+	var b uint64
+	_, b = bits.Sub64(glvHalfN[0], a[0], 0)
+	_, b = bits.Sub64(glvHalfN[1], a[1], b)
+	_, b = bits.Sub64(glvHalfN[2], a[2], b)
+	_, b = bits.Sub64(glvHalfN[3], a[3], b)
+	var d fel
+	var bb uint64
+	d[0], bb = bits.Sub64(fn.m[0], a[0], 0)
+	d[1], bb = bits.Sub64(fn.m[1], a[1], bb)
+	d[2], bb = bits.Sub64(fn.m[2], a[2], bb)
+	d[3], _ = bits.Sub64(fn.m[3], a[3], bb)
+	a.cmov(&d, b)
+	return a, b
+}
+
+// splitScalar returns k1, k2 (big-endian, less than 2^129) and their signs (1 for negative)
+// such that k = ±k1 ± k2*lambda mod N. k must be reduced modulo N. Constant time.
+func splitScalar(k *[32]byte) (k1, k2 [32]byte, neg1, neg2 uint64) {
+	kl := limbsFromBytes(k)
+	c1 := mulShift384(&kl, &glvG1)
+	c2 := mulShift384(&kl, &glvG2)
+	c1 = fn.mul(&c1, &fn.r2)
+	c2 = fn.mul(&c2, &fn.r2)
+	r2 := fn.mul(&c1, &glvMinusB1)
+	t := fn.mul(&c2, &glvMinusB2)
+	r2 = fn.add(&r2, &t)
+	r1 := fn.mul(&r2, &glvMinusLambda)
+	t = fn.mul(&kl, &fn.r2)
+	r1 = fn.add(&r1, &t)
+	a1, a2 := fn.mul(&r1, &fel{1}), fn.mul(&r2, &fel{1})
+	a1, neg1 = abs(a1)
+	a2, neg2 = abs(a2)
+	return a1.bytes(), a2.bytes(), neg1, neg2
+}
+
+// condNeg sets p to -p if c == 1.
+func (p *point) condNeg(c uint64) {
+	y := fp.neg(&p.y)
+	p.y.cmov(&y, c)
+}
+
+// endo sets p to lambda*a = (beta*X : Y : Z).
+func (p *point) endo(a *point) *point {
+	p.x, p.y, p.z = fp.mul(&a.x, &glvBeta), a.y, a.z
+	return p
+}
+
+// nibble returns the i-th 4-bit window of k (from the least significant).
+func nibble(k *[32]byte, i int) byte {
+	return k[31-i/2] >> (4 * (i % 2)) & 0x0f
+}
+
+// scalarMult sets p = k*a with the GLV endomorphism and the fixed 4-bit windows
+// of both halves of k (k is big-endian, 32 bytes, reduced modulo N). Constant time in k.
+func (p *point) scalarMult(a *point, k *[32]byte) *point {
+	k1, k2, neg1, neg2 := splitScalar(k)
+	var t1, t2 [16]point
+	a1 := *a
+	a1.condNeg(neg1)
+	t1[0].setInfinity()
+	t1[1] = a1
+	for i := 2; i < 16; i++ {
+		t1[i].add(&t1[i-1], &a1)
+	}
+	for i := range t2 {
+		t2[i].endo(&t1[i])
+		t2[i].condNeg(neg1 ^ neg2)
+	}
+	var r, t point
+	r.setInfinity()
+	for i := 32; i >= 0; i-- {
+		if i < 32 {
+			r.double(&r)
+			r.double(&r)
+			r.double(&r)
+			r.double(&r)
+		}
+		t.lookup(t1[:], nibble(&k1, i))
+		r.add(&r, &t)
+		t.lookup(t2[:], nibble(&k2, i))
+		r.add(&r, &t)
+	}
+	*p = r
+	return p
+}
+
+// The odd multiples G, 3G, ..., 63G and their endomorphisms for combinedMult, built on the first use.
+var (
+	baseOdd, baseOddEndo [32]point
+	baseOddOnce          sync.Once
+)
+
+func initBaseOdd() {
+	oddMultiples(baseOdd[:], newPoint(secp256k1.Gx, secp256k1.Gy))
+	for i := range baseOdd {
+		baseOddEndo[i].endo(&baseOdd[i])
+	}
+}
+
+// oddMultiples sets t to a, 3a, 5a, ...
+func oddMultiples(t []point, a *point) {
+	var a2 point
+	a2.double(a)
+	t[0] = *a
+	for i := 1; i < len(t); i++ {
+		t[i].add(&t[i-1], &a2)
+	}
+}
+
+// wnaf returns the width-w NAF of k (the least significant digit first).
+func wnaf(k []byte, w uint) []int8 {
+	x := new(big.Int).SetBytes(k)
+	naf := make([]int8, 0, x.BitLen()+1)
+	m := int64(1) << w
+	d := new(big.Int)
+	for x.Sign() > 0 {
+		var v int64
+		if x.Bit(0) == 1 {
+			v = int64(x.Uint64() & uint64(m-1))
+			if v >= m/2 {
+				v -= m
+			}
+			x.Sub(x, d.SetInt64(v))
+		}
+		naf = append(naf, int8(v))
+		x.Rsh(x, 1)
+	}
+	return naf
+}
+
+// combinedMult sets p = ka*G + kb*a with the GLV endomorphism and the interleaved wNAF (Strauss-Shamir) method.
+// Not constant time, only for public scalars (signature verification). ka and kb must be reduced modulo N.
+func (p *point) combinedMult(a *point, ka, kb *[32]byte) *point {
+	baseOddOnce.Do(initBaseOdd)
+	var aOdd, aOddEndo [8]point
+	oddMultiples(aOdd[:], a)
+	for i := range aOdd {
+		aOddEndo[i].endo(&aOdd[i])
+	}
+	a1, a2, na1, na2 := splitScalar(ka)
+	b1, b2, nb1, nb2 := splitScalar(kb)
+	type stream struct {
+		naf   []int8
+		table []point
+		neg   bool
+	}
+	ss := []stream{
+		{wnaf(a1[:], 7), baseOdd[:], na1 == 1},
+		{wnaf(a2[:], 7), baseOddEndo[:], na2 == 1},
+		{wnaf(b1[:], 5), aOdd[:], nb1 == 1},
+		{wnaf(b2[:], 5), aOddEndo[:], nb2 == 1},
+	}
+	var l int
+	for _, s := range ss {
+		l = max(l, len(s.naf))
+	}
+	var r, t point
+	r.setInfinity()
+	for i := l - 1; i >= 0; i-- {
+		r.double(&r)
+		for _, s := range ss {
+			if i >= len(s.naf) || s.naf[i] == 0 {
+				continue
+			}
+			d := s.naf[i]
+			if d < 0 {
+				t = s.table[-d/2]
+			} else {
+				t = s.table[d/2]
+			}
+			if (d < 0) != s.neg {
+				t.y = fp.neg(&t.y)
+			}
+			r.add(&r, &t)
+		}
+	}
+	*p = r
+	return p
+}
@@ -0,0 +1,93 @@
+package cckat
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"strings"
+)
+
+// The first header bytes of the BIP137 signatures for the address types, the recovery id (0 - 3) is added
+var msgHeaders = map[AddressType]byte{
+	P2PKHUncomp: 27,
+	P2PKH:       31,
+	P2SH:        35, // P2SH-P2WPKH
+	P2WPKH:      39,
+	P2SHP2WPKH:  35,
+}
+
+// appendVarInt appends the Bitcoin variable length integer (CompactSize) n to b.
+func appendVarInt(b []byte, n uint64) []byte {
+	switch {
+	case n < 0xfd:
+		return append(b, byte(n))
+	case n <= 0xffff:
+		return binary.LittleEndian.AppendUint16(append(b, 0xfd), uint16(n))
+	case n <= 0xffffffff:
+		return binary.LittleEndian.AppendUint32(append(b, 0xfe), uint32(n))
+	}
+	return binary.LittleEndian.AppendUint64(append(b, 0xff), n)
+}
+
+// MessageHash returns the hash of the message signed by SignMessage:
+// SHA256(SHA256(varstr("Bitcoin Signed Message:\n") || varstr(msg))).
+func MessageHash(msg string) []byte {
+	const magic = "Bitcoin Signed Message:\n"
+	b := appendVarInt(nil, uint64(len(magic)))
+	b = append(b, magic...)
+	b = appendVarInt(b, uint64(len(msg)))
+	return dsha256(append(b, msg...))
+}
+
+// SignMessage returns the base64 encoded compact signature of msg (BIP137) for the address of the type at
+// (P2PKH, P2PKHUncomp, P2SHP2WPKH (or P2SH) or P2WPKH). Returns UnsupAddrType for other types.
+func (k *PrKey) SignMessage(msg string, at AddressType) (string, error) {
+	h, ok := msgHeaders[at]
+	if !ok {
+		return "", UnsupAddrType
+	}
+	sig, recid, err := k.SignRecoverable(MessageHash(msg))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(append([]byte{h + recid}, sig.Compact()...)), nil
+}
+
+// VerifyMessage returns true if sig is a valid base64 encoded compact signature of msg for the address addr
+// of any network (see Networks). The public key is recovered from the signature and its address is compared
+// with addr. The BIP137 headers (27 - 42) and the Electrum style headers (27 - 34 for all the address types)
+// are accepted.
+func VerifyMessage(addr, msg, sig string) bool {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(sig))
+	if err != nil || len(b) != 65 || b[0] < 27 || b[0] > 42 {
+		return false
+	}
+	s, err := ParseCompact(b[1:])
+	if err != nil {
+		return false
+	}
+	p, err := RecoverPubKey(MessageHash(msg), s, (b[0]-27)&3)
+	if err != nil {
+		return false
+	}
+	var types []AddressType
+	switch {
+	case b[0] < 31:
+		types = []AddressType{P2PKHUncomp}
+	case b[0] < 35:
+		types = []AddressType{P2PKH, P2SHP2WPKH, P2WPKH}
+	case b[0] < 39:
+		types = []AddressType{P2SHP2WPKH}
+	default:
+		types = []AddressType{P2WPKH}
+	}
+	addr = strings.TrimSpace(addr)
+	for _, n := range Networks {
+		for _, t := range types {
+			a, err := addresses[t](p, n)
+			if err == nil && (a == addr || t == P2WPKH && strings.EqualFold(a, addr)) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package cckat
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+// The message signatures made with btcd (ecdsa.SignCompact): the key, the message, the compressed key signature
+// (header 31 - 34), the uncompressed key signature (header 27 - 30) and the P2PKH, P2PKH (uncompressed),
+// P2SH-P2WPKH and P2WPKH addresses.
+var messageTests = []struct {
+	key, msg, comp, uncomp string
+	addrs                  [4]string
+}{
+	{"040de27fd9eac744799376bc4b92a5d5b0c7e74f2a18cd5b5e271c3901c1c4ec", "This is an example of a signed message.",
+		"H0Kujulopf42Pi1mc+1oT7FgNAyCL9DnX3UyF9CqLZJYVCycxyWF7JGGXKZwY9uJ+ttOl64+j9c43aiOm/gathY=",
+		"G0Kujulopf42Pi1mc+1oT7FgNAyCL9DnX3UyF9CqLZJYVCycxyWF7JGGXKZwY9uJ+ttOl64+j9c43aiOm/gathY=",
+		[4]string{"1DuGdQSmPVJs5b1rQi7btekkSMYxEtnnDv", "1K19MxSa21TUuPPxyFk26WkaXxSNRhgQSk",
+			"31oHpcu7dtZXohUBM73K4ZVMTFAEiZQUAW", "bc1q3kzz7j58kw0hc6nlt9xymp4l2kqc500hjz75sa"}},
+	{"38bf52c0a9cea023aa890b51bd33a26548420aa8f1025d7cbe18e6adb6818b3b", "",
+		"IHc7sVMbL3yG0DGo9J4MeFb+ohSze/xYhsT3h2gnloDhYabGjBfbBUG2R8ure12kM/praJRZAY0wwwxmRS6ll3g=",
+		"HHc7sVMbL3yG0DGo9J4MeFb+ohSze/xYhsT3h2gnloDhYabGjBfbBUG2R8ure12kM/praJRZAY0wwwxmRS6ll3g=",
+		[4]string{"1DFP5tw2Xu78DqZC8E48kj2taRVPJB1rL1", "148bBauhQrQB5Lc7CnFr5twxxTk9qtzZDv",
+			"3KqjaejykZ53ERDNX7A98Znht3Y4HiGL8d", "bc1qsevufx7qx4ycyvxjfkjvxfvt7p8xt66dzlug4p"}},
+	{"4a1afed74314175380582a1ece29a64d71985f761afa9ef86b8eb2c698967259", strings.Repeat("x", 300),
+		"H4gpLwgo3yazIrBMq+3O1C4YeuWFWr9h1S+uWO+B1kbVVurlKWh10Tz24QoiKNwGLDB4xtbuXQlvV0o7mL2ceI8=",
+		"G4gpLwgo3yazIrBMq+3O1C4YeuWFWr9h1S+uWO+B1kbVVurlKWh10Tz24QoiKNwGLDB4xtbuXQlvV0o7mL2ceI8=",
+		[4]string{"1EbZvjL3tAHTCbDXmDiwExfqTvKD8nZyaF", "1CnEUFwAZitJNtEmRc8wQNbKbPJ4yFCkXo",
+			"3E43scN1x7C6Jqwu8PiG831J7kp3EvPA2s", "bc1qj535xugk7htrcunt67ych0v90898zqkvx5k4n5"}},
+}
+
+// setHeader returns the base64 signature sig with the header byte h + the recovery id of sig.
+func setHeader(t *testing.T, sig string, h byte) string {
+	t.Helper()
+	b, err := base64.StdEncoding.DecodeString(sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b[0] = h + (b[0]-27)&3
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+func TestSignMessage(t *testing.T) {
+	for _, c := range messageTests {
+		k := testKey(t, c.key)
+		for i, at := range []AddressType{P2PKH, P2PKHUncomp, P2SHP2WPKH, P2WPKH} {
+			want := setHeader(t, c.comp, msgHeaders[at])
+			sig, err := k.SignMessage(c.msg, at)
+			if err != nil || sig != want {
+				t.Errorf("%s: type %d: got %s, %v, want %s", c.addrs[0], at, sig, err, want)
+			}
+			if !VerifyMessage(c.addrs[i], c.msg, sig) {
+				t.Errorf("%s: type %d: not verified", c.addrs[i], at)
+			}
+		}
+		if sig, _ := k.SignMessage(c.msg, P2SH); sig != setHeader(t, c.comp, 35) {
+			t.Errorf("%s: P2SH: got %s", c.addrs[2], sig)
+		}
+		if sig, _ := k.SignMessage(c.msg, P2PKHUncomp); sig != c.uncomp {
+			t.Errorf("%s: uncompressed: got %s", c.addrs[1], sig)
+		}
+		if _, err := k.SignMessage(c.msg, P2TR); err != UnsupAddrType {
+			t.Errorf("P2TR: %v", err)
+		}
+	}
+}
+
+func TestVerifyMessage(t *testing.T) {
+	for i, c := range messageTests {
+		// the Electrum style headers: the compressed key header for the segwit addresses
+		for _, a := range []string{c.addrs[0], c.addrs[2], c.addrs[3], strings.ToUpper(c.addrs[3])} {
+			if !VerifyMessage(a, c.msg, c.comp) {
+				t.Errorf("%s: Electrum header not verified", a)
+			}
+		}
+		if !VerifyMessage(" "+c.addrs[1]+"\n", c.msg, c.uncomp+"\n") {
+			t.Errorf("%s: not verified", c.addrs[1])
+		}
+		other := messageTests[(i+1)%len(messageTests)]
+		for name, v := range map[string][3]string{
+			"wrong address":                 {other.addrs[0], c.msg, c.comp},
+			"wrong address (uncompressed)":  {other.addrs[1], c.msg, c.uncomp},
+			"wrong message":                 {c.addrs[0], c.msg + " ", c.comp},
+			"uncompressed header":           {c.addrs[0], c.msg, c.uncomp},
+			"compressed header":             {c.addrs[1], c.msg, c.comp},
+			"P2WPKH header for P2PKH":       {c.addrs[0], c.msg, setHeader(t, c.comp, 39)},
+			"P2WPKH header for P2SH-P2WPKH": {c.addrs[2], c.msg, setHeader(t, c.comp, 39)},
+			"P2SH-P2WPKH header for P2WPKH": {c.addrs[3], c.msg, setHeader(t, c.comp, 35)},
+			"wrong recovery id":             {c.addrs[0], c.msg, setHeader(t, c.comp, 32)},
+			"header below 27":               {c.addrs[1], c.msg, setHeader(t, c.comp, 23)},
+			"header above 42":               {c.addrs[3], c.msg, setHeader(t, c.comp, 43)},
+			"short":                         {c.addrs[0], c.msg, c.comp[:84]},
+			"not base64":                    {c.addrs[0], c.msg, "!" + c.comp[1:]},
+		} {
+			if VerifyMessage(v[0], v[1], v[2]) {
+				t.Errorf("%s: %s verified", c.addrs[0], name)
+			}
+		}
+	}
+}
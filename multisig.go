@@ -0,0 +1,110 @@
+package cckat
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"slices"
+)
+
+var (
+	InvMultisig  = errors.New("invalid multisig parameters")
+	UncompSegwit = errors.New("uncompressed public key in segwit script")
+)
+
+// maxWitnessScriptSize is the maximum standard size of the P2WSH witness script.
+const maxWitnessScriptSize = 3600
+
+// MultisigScript returns the m-of-n script OP_m <pubKey 1> ... <pubKey n> OP_n OP_CHECKMULTISIG (1 <= m <= n <= 20).
+// The public keys (any format accepted by ParsePubKey) of 65 bytes are used in the uncompressed format,
+// the others in the compressed format. The keys are sorted lexicographically (BIP67) if sorted is true,
+// pubKeys is not changed.
+func MultisigScript(m int, pubKeys [][]byte, sorted bool) ([]byte, error) {
+	keys := make([][]byte, len(pubKeys))
+	for i, pk := range pubKeys {
+		p, err := PubKeyCompUncomp(pk, len(pk) != 65)
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = p
+	}
+	return multisigScript(m, keys, sorted)
+}
+
+// multisigScript returns the m-of-n script of the serialized public keys.
+func multisigScript(m int, keys [][]byte, sorted bool) ([]byte, error) {
+	n := len(keys)
+	if m < 1 || m > n || n > 20 {
+		return nil, InvMultisig
+	}
+	if sorted {
+		keys = slices.Clone(keys)
+		slices.SortFunc(keys, bytes.Compare)
+	}
+	s := appendSmallInt(nil, m)
+	for _, k := range keys {
+		s = appendPush(s, k)
+	}
+	return append(appendSmallInt(s, n), 0xae), nil
+}
+
+// MultisigAddress returns the address of the type t (P2SH, P2WSH or P2SHP2WSH) for the network n of the m-of-n
+// multisig script (see MultisigScript) and the script: the redeem script for P2SH or the witness script for
+// P2WSH and P2SHP2WSH (the redeem script of P2SHP2WSH is returned by RedeemScriptP2SHP2WSH).
+// The uncompressed public keys are allowed only for P2SH, UncompSegwit is returned otherwise.
+func MultisigAddress(m int, pubKeys [][]byte, sorted bool, t AddressType, n *Network) (string, []byte, error) {
+	s, err := MultisigScript(m, pubKeys, sorted)
+	if err != nil {
+		return "", nil, err
+	}
+	if t == P2WSH || t == P2SHP2WSH {
+		for _, pk := range pubKeys {
+			if len(pk) == 65 {
+				return "", nil, UncompSegwit
+			}
+		}
+	}
+	var a string
+	switch t {
+	case P2SH:
+		a, err = ScriptAddressP2SH(s, n)
+	case P2WSH:
+		a, err = ScriptAddressP2WSH(s, n)
+	case P2SHP2WSH:
+		a, err = ScriptAddressP2SHP2WSH(s, n)
+	default:
+		return "", nil, UnsupAddrType
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	return a, s, nil
+}
+
+// ScriptAddressP2WSH returns the Pay-to-witness-script-hash address of the witness script for the network n.
+// Returns InvScriptLen if the script is empty or longer than 3600 bytes (the standard limit).
+func ScriptAddressP2WSH(witnessScript []byte, n *Network) (string, error) {
+	if n.Bech32HRP == "" {
+		return "", NoSegwit
+	}
+	if len(witnessScript) == 0 || len(witnessScript) > maxWitnessScriptSize {
+		return "", InvScriptLen
+	}
+	h := sha256.Sum256(witnessScript)
+	return Bech32mencode(h[:], n.Bech32HRP, 0), nil
+}
+
+// ScriptAddressP2SHP2WSH returns the Pay-to-witness-script-hash nested in Pay-to-script-hash address
+// of the witness script for the network n.
+func ScriptAddressP2SHP2WSH(witnessScript []byte, n *Network) (string, error) {
+	if len(witnessScript) == 0 || len(witnessScript) > maxWitnessScriptSize {
+		return "", InvScriptLen
+	}
+	return ScriptAddressP2SH(RedeemScriptP2SHP2WSH(witnessScript), n)
+}
+
+// RedeemScriptP2SHP2WSH returns the redeem script of the P2SH-P2WSH address: OP_0 <SHA256 of script>.
+func RedeemScriptP2SHP2WSH(script []byte) []byte {
+	h := sha256.Sum256(script)
+	return witnessScript(0, h[:])
+}
@@ -0,0 +1,108 @@
+package cckat
+
+import (
+	"bytes"
+	"encoding/hex"
+	"slices"
+	"testing"
+)
+
+// The BIP67 test vectors, the P2WSH and P2SH-P2WSH addresses are computed by btcd.
+var multisigTests = []struct {
+	m                    int
+	keys                 []string
+	script               string
+	p2sh, p2wsh, p2shWsh string
+}{
+	{2, []string{
+		"02ff12471208c14bd580709cb2358d98975247d8765f92bc25eab3b2763ed605f8",
+		"02fe6f0a5a297eb38c391581c4413e084773ea23954d93f7753db7dc0adc188b2f",
+	}, "522102fe6f0a5a297eb38c391581c4413e084773ea23954d93f7753db7dc0adc188b2f2102ff12471208c14bd580709cb2358d98975247d8765f92bc25eab3b2763ed605f852ae",
+		"39bgKC7RFbpoCRbtD5KEdkYKtNyhpsNa3Z", "bc1qknwt9mhqpd7hrjrvpqz57zjqk28xlp2h90te6v22en0m3uctnams3pq5ce",
+		"3BBLivaThSP3C31jzmQJiMWBM7BLndaWfh"},
+	{2, []string{
+		"02632b12f4ac5b1d1b72b2a3b508c19172de44f6f46bcee50ba33f3f9291e47ed0",
+		"027735a29bae7780a9755fae7a1c4374c656ac6a69ea9f3697fda61bb99a4f3e77",
+		"02e2cc6bd5f45edd43bebe7cb9b675f0ce9ed3efe613b177588290ad188d11b404",
+	}, "522102632b12f4ac5b1d1b72b2a3b508c19172de44f6f46bcee50ba33f3f9291e47ed021027735a29bae7780a9755fae7a1c4374c656ac6a69ea9f3697fda61bb99a4f3e772102e2cc6bd5f45edd43bebe7cb9b675f0ce9ed3efe613b177588290ad188d11b40453ae",
+		"3CKHTjBKxCARLzwABMu9yD85kvtm7WnMfH", "bc1qud6dmdcc27eg8s5hsy6a075gs49w65l6xtc4cplp6m2d4ggh43wqew2vqs",
+		"31iXMTVFX7qKnPnGVx2ZmJYWuNy3BiCNHS"},
+	{2, []string{
+		"030000000000000000000000000000000000004141414141414141414141414141",
+		"020000000000000000000000000000000000004141414141414141414141414141",
+		"020000000000000000000000000000000000004141414141414141414141414140",
+		"030000000000000000000000000000000000004141414141414141414141414140",
+	}, "522102000000000000000000000000000000000000414141414141414141414141414021020000000000000000000000000000000000004141414141414141414141414141210300000000000000000000000000000000000041414141414141414141414141402103000000000000000000000000000000000000414141414141414141414141414154ae",
+		"32V85igBri9zcfBRVupVvwK18NFtS37FuD", "bc1q43l9uw4l5q3d3eltdvf785atcpfys8wad6z4rv6mltnzrzasq0jqp0lwze",
+		"3PhpcqwVTnKJMiTnAgoFWnRvoPMKhdHfdL"},
+	{2, []string{
+		"022df8750480ad5b26950b25c7ba79d3e37d75f640f8e5d9bcd5b150a0f85014da",
+		"03e3818b65bcc73a7d64064106a859cc1a5a728c4345ff0b641209fba0d90de6e9",
+		"021f2f6e1e50cb6a953935c3601284925decd3fd21bc445712576873fb8c6ebc18",
+	}, "5221021f2f6e1e50cb6a953935c3601284925decd3fd21bc445712576873fb8c6ebc1821022df8750480ad5b26950b25c7ba79d3e37d75f640f8e5d9bcd5b150a0f85014da2103e3818b65bcc73a7d64064106a859cc1a5a728c4345ff0b641209fba0d90de6e953ae",
+		"3Q4sF6tv9wsdqu2NtARzNCpQgwifm2rAba", "bc1q0uyls9kc4acv9ntqw6u096t53jlld4frp4rscrf8fruddhu62p6sy9507s",
+		"38Yre4Cmxe9EfAr71nGSQjRS5GJ6BikhoU"},
+	// the uncompressed G
+	{1, []string{"04" + gX + gY}, "514104" + gX + gY + "51ae", "33RjLdp9usumz3BNqa5PB9umJZjiw7kmjK", "", ""},
+}
+
+func TestMultisigAddress(t *testing.T) {
+	for _, c := range multisigTests {
+		var keys [][]byte
+		for _, k := range c.keys {
+			keys = append(keys, mustHex(t, k))
+		}
+		orig := slices.Clone(keys)
+		for _, at := range []struct {
+			t    AddressType
+			addr string
+		}{{P2SH, c.p2sh}, {P2WSH, c.p2wsh}, {P2SHP2WSH, c.p2shWsh}} {
+			a, s, err := MultisigAddress(c.m, keys, true, at.t, BTCMain)
+			if at.addr == "" {
+				if err != UncompSegwit {
+					t.Errorf("%s %d: %s, %v, want UncompSegwit", c.p2sh, at.t, a, err)
+				}
+				continue
+			}
+			if err != nil || a != at.addr || hex.EncodeToString(s) != c.script {
+				t.Errorf("%s %d: %s %x, %v, want %s", c.p2sh, at.t, a, s, err, at.addr)
+			}
+		}
+		if !slices.EqualFunc(keys, orig, slices.Equal) {
+			t.Errorf("%s: keys changed", c.p2sh)
+		}
+		if s, err := MultisigScript(c.m, keys, false); err != nil || slices.IsSortedFunc(keys, bytes.Compare) != (hex.EncodeToString(s) == c.script) {
+			t.Errorf("%s: unsorted script %x, %v", c.p2sh, s, err)
+		}
+	}
+
+	// the testnet address
+	if a, _, err := MultisigAddress(2, [][]byte{mustHex(t, multisigTests[0].keys[0]), mustHex(t, multisigTests[0].keys[1])},
+		true, P2WSH, BTCTest); err != nil || a != "tb1qknwt9mhqpd7hrjrvpqz57zjqk28xlp2h90te6v22en0m3uctnamsxfkmzk" {
+		t.Errorf("testnet P2WSH: %s, %v", a, err)
+	}
+}
+
+func TestMultisigInvalid(t *testing.T) {
+	g := mustHex(t, "02"+gX)
+	g2 := mustHex(t, "02"+g2X)
+	for _, c := range []struct {
+		name string
+		m    int
+		keys [][]byte
+		t    AddressType
+		err  error
+	}{
+		{"m 0", 0, [][]byte{g, g2}, P2SH, InvMultisig},
+		{"m > n", 3, [][]byte{g, g2}, P2SH, InvMultisig},
+		{"no keys", 1, nil, P2SH, InvMultisig},
+		{"n > 20", 1, slices.Repeat([][]byte{g}, 21), P2SH, InvMultisig},
+		{"invalid key", 1, [][]byte{g[1:3]}, P2SH, InvPubKeyF},
+		{"uncompressed P2WSH", 1, [][]byte{g, mustHex(t, "04"+gX+gY)}, P2WSH, UncompSegwit},
+		{"P2PKH", 1, [][]byte{g}, P2PKH, UnsupAddrType},
+	} {
+		if a, _, err := MultisigAddress(c.m, c.keys, false, c.t, BTCMain); err != c.err {
+			t.Errorf("%s: %s, %v, want %v", c.name, a, err, c.err)
+		}
+	}
+}
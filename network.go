@@ -0,0 +1,101 @@
+package cckat
+
+import "errors"
+
+var NoSegwit = errors.New("segwit is not supported by the network")
+
+// Network holds the parameters of a network used to encode private keys and addresses.
+type Network struct {
+	Name       string
+	PubKeyHash byte   // the version byte of P2PKH addresses
+	ScriptHash byte   // the version byte of P2SH addresses
+	PrivateKey byte   // the version byte of WIF private keys
+	Bech32HRP  string // the human-readable part of segwit addresses, "" if segwit is not supported
+	HDPrivate  uint32 // the version bytes of extended private keys (BIP32)
+	HDPublic   uint32 // the version bytes of extended public keys (BIP32)
+}
+
+// Supported networks
+var (
+	BTCMain = &Network{
+		Name:       "bitcoin",
+		PubKeyHash: 0x00,
+		ScriptHash: 0x05,
+		PrivateKey: 0x80,
+		Bech32HRP:  "bc",
+		HDPrivate:  0x0488ade4,
+		HDPublic:   0x0488b21e,
+	}
+	BTCTest = &Network{
+		Name:       "testnet",
+		PubKeyHash: 0x6f,
+		ScriptHash: 0xc4,
+		PrivateKey: 0xef,
+		Bech32HRP:  "tb",
+		HDPrivate:  0x04358394,
+		HDPublic:   0x043587cf,
+	}
+	BTCSig = &Network{
+		Name:       "signet",
+		PubKeyHash: 0x6f,
+		ScriptHash: 0xc4,
+		PrivateKey: 0xef,
+		Bech32HRP:  "tb",
+		HDPrivate:  0x04358394,
+		HDPublic:   0x043587cf,
+	}
+	BTCReg = &Network{
+		Name:       "regtest",
+		PubKeyHash: 0x6f,
+		ScriptHash: 0xc4,
+		PrivateKey: 0xef,
+		Bech32HRP:  "bcrt",
+		HDPrivate:  0x04358394,
+		HDPublic:   0x043587cf,
+	}
+	LTCMain = &Network{
+		Name:       "litecoin",
+		PubKeyHash: 0x30,
+		ScriptHash: 0x32,
+		PrivateKey: 0xb0,
+		Bech32HRP:  "ltc",
+		HDPrivate:  0x019d9cfe,
+		HDPublic:   0x019da462,
+	}
+	DOGEMain = &Network{
+		Name:       "dogecoin",
+		PubKeyHash: 0x1e,
+		ScriptHash: 0x16,
+		PrivateKey: 0x9e,
+		HDPrivate:  0x02fac398,
+		HDPublic:   0x02facafd,
+	}
+)
+
+// Networks is the list of the networks used to detect the network of a WIF or extended (BIP32) key.
+// If several networks use the same version byte, the first one is selected
+// (BTCTest for testnet, signet and regtest keys, see PrKey.SetNetwork and ExtendedKey.SetNetwork).
+var Networks = []*Network{BTCMain, BTCTest, BTCSig, BTCReg, LTCMain, DOGEMain}
+
+// networkByWIF returns the network with the WIF version byte b or nil.
+func networkByWIF(b byte) *Network {
+	for _, n := range Networks {
+		if n.PrivateKey == b {
+			return n
+		}
+	}
+	return nil
+}
+
+// networkByHD returns the network with the extended key version v and true if v is the private key version or nil.
+func networkByHD(v uint32) (*Network, bool) {
+	for _, n := range Networks {
+		if n.HDPrivate == v {
+			return n, true
+		}
+		if n.HDPublic == v {
+			return n, false
+		}
+	}
+	return nil, false
+}
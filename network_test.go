@@ -0,0 +1,65 @@
+package cckat
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNoSegwit(t *testing.T) {
+	p := testKey(t, "0000000000000000000000000000000000000000000000000000000000000001").PubK()
+	for _, at := range []AddressType{P2SHP2WPKH, P2WPKH, P2TR} {
+		if _, err := GetAddress(p, at, DOGEMain); err != NoSegwit {
+			t.Errorf("%d: %v", at, err)
+		}
+	}
+	if _, err := GetAddress(p, P2PKH, DOGEMain); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRegtestKeys(t *testing.T) {
+	k := testKey(t, "0000000000000000000000000000000000000000000000000000000000000001").SetNetwork(BTCReg)
+	w := k.WIF()
+	k2, err := new(PrKey).SetWIF(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k2.Network() != BTCTest {
+		t.Errorf("WIF network %s, want testnet", k2.Network().Name)
+	}
+	if a, _ := GetAddress(k2.SetNetwork(BTCReg).PubK(), P2WPKH, k2.Network()); !strings.HasPrefix(a, "bcrt1") {
+		t.Errorf("regtest address %s", a)
+	}
+
+	m, err := NewMasterKey(make([]byte, 32), BTCReg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := ParseExtendedKey(m.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Network() != BTCTest {
+		t.Errorf("extended key network %s, want testnet", e.Network().Name)
+	}
+	c, err := e.SetNetwork(BTCReg).Derive("m/84'/1'/0'/0/0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Network() != BTCReg || c.String() != mustDerive(t, m, "m/84'/1'/0'/0/0").String() {
+		t.Errorf("derived key network %s", c.Network().Name)
+	}
+	pk, _ := c.PrKey()
+	if pk.Network() != BTCReg {
+		t.Errorf("derived private key network %s", pk.Network().Name)
+	}
+}
+
+func mustDerive(t *testing.T, e *ExtendedKey, path string) *ExtendedKey {
+	t.Helper()
+	c, err := e.Derive(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
@@ -0,0 +1,200 @@
+package cckat
+
+import (
+	"math/big"
+	"sync"
+)
+
+// point is a secp256k1 point in homogeneous projective coordinates (x = X/Z, y = Y/Z, Montgomery form).
+// The point at infinity is (0 : 1 : 0).
+// The complete formulas of Renes, Costello and Batina (https://eprint.iacr.org/2015/1060, a = 0) have
+// no exceptional cases, so the operations are constant time and don't branch on the coordinates.
+type point struct {
+	x, y, z fel
+}
+
+// b3 is 3*B in Montgomery form
+var b3 = fp.fromBig(big.NewInt(21))
+
+// newPoint returns the point (x, y). (0, 0) is the point at infinity.
+func newPoint(x, y *big.Int) *point {
+	p := new(point)
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return p.setInfinity()
+	}
+	if x.Sign() < 0 || x.Cmp(secp256k1.P) >= 0 {
+		x = new(big.Int).Mod(x, secp256k1.P)
+	}
+	if y.Sign() < 0 || y.Cmp(secp256k1.P) >= 0 {
+		y = new(big.Int).Mod(y, secp256k1.P)
+	}
+	p.x, p.y, p.z = fp.fromBig(x), fp.fromBig(y), fp.one
+	return p
+}
+
+func (p *point) setInfinity() *point {
+	p.x, p.y, p.z = fel{}, fp.one, fel{}
+	return p
+}
+
+// affine returns the affine coordinates of p, (0, 0) for the point at infinity.
+func (p *point) affine() (x, y *big.Int) {
+	if p.z.isZero() == 1 {
+		return new(big.Int), new(big.Int)
+	}
+	zi := fp.inv(&p.z)
+	ax, ay := fp.mul(&p.x, &zi), fp.mul(&p.y, &zi)
+	return fp.toBig(&ax), fp.toBig(&ay)
+}
+
+// add sets p = a + b (algorithm 7 of RCB). p may alias a or b.
+func (p *point) add(a, b *point) *point {
+	t0 := fp.mul(&a.x, &b.x)
+	t1 := fp.mul(&a.y, &b.y)
+	t2 := fp.mul(&a.z, &b.z)
+	u, v := fp.add(&a.x, &a.y), fp.add(&b.x, &b.y)
+	t3 := fp.mul(&u, &v)
+	u = fp.add(&t0, &t1)
+	t3 = fp.sub(&t3, &u) // X1*Y2 + X2*Y1
+	u, v = fp.add(&a.y, &a.z), fp.add(&b.y, &b.z)
+	t4 := fp.mul(&u, &v)
+	u = fp.add(&t1, &t2)
+	t4 = fp.sub(&t4, &u) // Y1*Z2 + Y2*Z1
+	u, v = fp.add(&a.x, &a.z), fp.add(&b.x, &b.z)
+	t5 := fp.mul(&u, &v)
+	u = fp.add(&t0, &t2)
+	t5 = fp.sub(&t5, &u) // X1*Z2 + X2*Z1
+	x3 := fp.add(&t0, &t0)
+	t0 = fp.add(&x3, &t0) // 3*X1*X2
+	t2 = fp.mul(&b3, &t2)
+	z3 := fp.add(&t1, &t2)
+	t1 = fp.sub(&t1, &t2)
+	t5 = fp.mul(&b3, &t5)
+	x3 = fp.mul(&t4, &t5)
+	u = fp.mul(&t3, &t1)
+	x3 = fp.sub(&u, &x3)
+	y3 := fp.mul(&t5, &t0)
+	u = fp.mul(&t1, &z3)
+	y3 = fp.add(&u, &y3)
+	t0 = fp.mul(&t0, &t3)
+	z3 = fp.mul(&z3, &t4)
+	z3 = fp.add(&z3, &t0)
+	p.x, p.y, p.z = x3, y3, z3
+	return p
+}
+
+// double sets p = 2a (algorithm 9 of RCB). p may alias a.
+func (p *point) double(a *point) *point {
+	t0 := fp.sqr(&a.y)
+	z3 := fp.add(&t0, &t0)
+	z3 = fp.add(&z3, &z3)
+	z3 = fp.add(&z3, &z3) // 8*Y^2
+	t1 := fp.mul(&a.y, &a.z)
+	t2 := fp.sqr(&a.z)
+	t2 = fp.mul(&b3, &t2)
+	x3 := fp.mul(&t2, &z3)
+	y3 := fp.add(&t0, &t2)
+	z3 = fp.mul(&t1, &z3)
+	t1 = fp.add(&t2, &t2)
+	t2 = fp.add(&t1, &t2)
+	t0 = fp.sub(&t0, &t2)
+	y3 = fp.mul(&t0, &y3)
+	y3 = fp.add(&x3, &y3)
+	t1 = fp.mul(&a.x, &a.y)
+	x3 = fp.mul(&t0, &t1)
+	x3 = fp.add(&x3, &x3)
+	p.x, p.y, p.z = x3, y3, z3
+	return p
+}
+
+// cmov sets p to a if c == 1 and leaves p unchanged if c == 0.
+func (p *point) cmov(a *point, c uint64) {
+	p.x.cmov(&a.x, c)
+	p.y.cmov(&a.y, c)
+	p.z.cmov(&a.z, c)
+}
+
+// lookup sets p to table[i] reading all the entries of the table.
+func (p *point) lookup(table []point, i byte) *point {
+	for j := range table {
+		d := uint64(byte(j) ^ i)
+		p.cmov(&table[j], 1^(d|-d)>>63)
+	}
+	return p
+}
+
+// baseTable[i][j] is j*16^i*G, it is built on the first use of ScalarBaseMult (96 KiB).
+var (
+	baseTable     *[64][16]point
+	baseTableOnce sync.Once
+)
+
+func initBaseTable() {
+	t := new([64][16]point)
+	g := newPoint(secp256k1.Gx, secp256k1.Gy)
+	for i := range t {
+		t[i][0].setInfinity()
+		t[i][1] = *g
+		for j := 2; j < 16; j++ {
+			t[i][j].add(&t[i][j-1], g)
+		}
+		g.add(&t[i][15], g)
+	}
+	baseTable = t
+}
+
+// scalarBaseMult sets p = k*G with the precomputed table: one constant time lookup and addition
+// for each 4-bit window of k, no doublings (k is big-endian, 32 bytes).
+func (p *point) scalarBaseMult(k *[32]byte) *point {
+	baseTableOnce.Do(initBaseTable)
+	var r, t point
+	r.setInfinity()
+	for i := 0; i < 64; i++ {
+		t.lookup(baseTable[i][:], k[31-i/2]>>(4*(i%2))&0x0f)
+		r.add(&r, &t)
+	}
+	*p = r
+	return p
+}
+
+// batchAffine returns the affine coordinates of the points ps (X || Y, big-endian) using one field inversion
+// for all the points (Montgomery's simultaneous inversion). The point at infinity is returned as (0, 0).
+func batchAffine(ps []point) [][64]byte {
+	acc := make([]fel, len(ps)) // acc[i] = Z0*...*Z(i-1)
+	t := fp.one
+	for i := range ps {
+		z := ps[i].z
+		z.cmov(&fp.one, z.isZero())
+		acc[i] = t
+		t = fp.mul(&t, &z)
+	}
+	ti := fp.inv(&t)
+	res := make([][64]byte, len(ps))
+	for i := len(ps) - 1; i >= 0; i-- {
+		z := ps[i].z
+		inf := z.isZero()
+		z.cmov(&fp.one, inf)
+		zi := fp.mul(&ti, &acc[i])
+		ti = fp.mul(&ti, &z)
+		x, y := fp.mul(&ps[i].x, &zi), fp.mul(&ps[i].y, &zi)
+		x.cmov(&fel{}, inf)
+		y.cmov(&fel{}, inf)
+		xb, yb := fp.toBytes(&x), fp.toBytes(&y)
+		copy(res[i][:32], xb[:])
+		copy(res[i][32:], yb[:])
+	}
+	return res
+}
+
+// scalarBytes returns k mod N as 32 bytes (k is big-endian).
+// Only the keys longer than 32 bytes are reduced with big.Int.
+func scalarBytes(k []byte) [32]byte {
+	var b [32]byte
+	if len(k) > 32 {
+		new(big.Int).Mod(new(big.Int).SetBytes(k), secp256k1.N).FillBytes(b[:])
+		return b
+	}
+	copy(b[32-len(k):], k)
+	l := fn.reduce(limbsFromBytes(&b), 0)
+	return l.bytes()
+}
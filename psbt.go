@@ -0,0 +1,824 @@
+package cckat
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"slices"
+	"strings"
+)
+
+var (
+	InvPSBT      = errors.New("invalid PSBT")
+	PSBTNoUTXO   = errors.New("previous output of the PSBT input is missing")
+	PSBTNotFinal = errors.New("PSBT is not finalized")
+)
+
+var psbtMagic = []byte("psbt\xff")
+
+// The PSBT key types
+const (
+	psbtGlobalUnsignedTx       = 0x00
+	psbtGlobalXpub             = 0x01
+	psbtGlobalTxVersion        = 0x02
+	psbtGlobalFallbackLockTime = 0x03
+	psbtGlobalInputCount       = 0x04
+	psbtGlobalOutputCount      = 0x05
+	psbtGlobalTxModifiable     = 0x06
+	psbtGlobalVersion          = 0xfb
+
+	psbtInNonWitnessUTXO     = 0x00
+	psbtInWitnessUTXO        = 0x01
+	psbtInPartialSig         = 0x02
+	psbtInSigHashType        = 0x03
+	psbtInRedeemScript       = 0x04
+	psbtInWitnessScript      = 0x05
+	psbtInBIP32Derivation    = 0x06
+	psbtInFinalScriptSig     = 0x07
+	psbtInFinalScriptWitness = 0x08
+	psbtInPrevTxID           = 0x0e
+	psbtInOutputIndex        = 0x0f
+	psbtInSequence           = 0x10
+	psbtInRequiredTimeLock   = 0x11
+	psbtInRequiredHeightLock = 0x12
+	psbtInTapKeySig          = 0x13
+	psbtInTapScriptSig       = 0x14
+	psbtInTapLeafScript      = 0x15
+	psbtInTapBIP32Derivation = 0x16
+	psbtInTapInternalKey     = 0x17
+	psbtInTapMerkleRoot      = 0x18
+
+	psbtOutRedeemScript       = 0x00
+	psbtOutWitnessScript      = 0x01
+	psbtOutBIP32Derivation    = 0x02
+	psbtOutAmount             = 0x03
+	psbtOutScript             = 0x04
+	psbtOutTapInternalKey     = 0x05
+	psbtOutTapBIP32Derivation = 0x07
+)
+
+// PSBT is a partially signed Bitcoin transaction of version 0 (BIP174) or 2 (BIP370).
+// The unsigned transaction of version 0 is kept in the fields of PSBT, PSBTInput and PSBTOutput as for version 2.
+// See https://github.com/bitcoin/bips/blob/master/bip-0174.mediawiki
+type PSBT struct {
+	Version      uint32 // 0 or 2
+	TxVersion    int32
+	LockTime     uint32 // the locktime of version 0 or the fallback locktime of version 2
+	TxModifiable byte   // version 2
+	Inputs       []*PSBTInput
+	Outputs      []*PSBTOutput
+	Unknown      []PSBTKV // the other global pairs (xpubs, proprietary etc.)
+}
+
+// PSBTKV is a raw key-value pair of a PSBT map, Key includes the key type.
+type PSBTKV struct {
+	Key, Value []byte
+}
+
+// PSBTInput is a PSBT input. The nil fields are not set.
+type PSBTInput struct {
+	PrevOut            OutPoint
+	Sequence           uint32
+	RequiredTimeLock   uint32 // version 2, 0 if not set
+	RequiredHeightLock uint32 // version 2, 0 if not set
+	NonWitnessUTXO     *Tx
+	WitnessUTXO        *TxOut
+	PartialSigs        []PartialSig
+	SigHash            *SigHashType
+	RedeemScript       []byte
+	WitnessScript      []byte
+	FinalScriptSig     []byte
+	FinalScriptWitness [][]byte
+	TapKeySig          []byte
+	TapInternalKey     []byte // X-only
+	TapMerkleRoot      []byte
+	Unknown            []PSBTKV // the other pairs (BIP32 derivations, taproot script path data etc.)
+}
+
+// PartialSig is an ECDSA signature (DER || sighash type) of a PSBT input with the public key.
+type PartialSig struct {
+	PubKey, Sig []byte
+}
+
+// PSBTOutput is a PSBT output. The nil fields are not set.
+type PSBTOutput struct {
+	Value          int64
+	PkScript       []byte
+	RedeemScript   []byte
+	WitnessScript  []byte
+	TapInternalKey []byte   // X-only
+	Unknown        []PSBTKV // the other pairs (BIP32 derivations, taproot tree etc.)
+}
+
+// NewPSBT returns the PSBT of version 0 for the unsigned transaction tx.
+// Returns InvPSBT if tx has a scriptSig or a witness.
+func NewPSBT(tx *Tx) (*PSBT, error) {
+	p := &PSBT{TxVersion: tx.Version, LockTime: tx.LockTime}
+	for _, in := range tx.TxIn {
+		if len(in.ScriptSig) != 0 || len(in.Witness) != 0 {
+			return nil, InvPSBT
+		}
+		p.Inputs = append(p.Inputs, &PSBTInput{PrevOut: in.PrevOut, Sequence: in.Sequence})
+	}
+	for _, out := range tx.TxOut {
+		p.Outputs = append(p.Outputs, &PSBTOutput{Value: out.Value, PkScript: out.PkScript})
+	}
+	return p, nil
+}
+
+// UnsignedTx returns the unsigned transaction of p. The locktime of version 2 is computed from the required
+// locktimes of the inputs (BIP370), InvPSBT is returned if they are incompatible.
+func (p *PSBT) UnsignedTx() (*Tx, error) {
+	lt, err := p.lockTime()
+	if err != nil {
+		return nil, err
+	}
+	tx := &Tx{Version: p.TxVersion, LockTime: lt}
+	for _, in := range p.Inputs {
+		tx.TxIn = append(tx.TxIn, &TxIn{PrevOut: in.PrevOut, Sequence: in.Sequence})
+	}
+	for _, out := range p.Outputs {
+		tx.TxOut = append(tx.TxOut, &TxOut{Value: out.Value, PkScript: out.PkScript})
+	}
+	return tx, nil
+}
+
+func (p *PSBT) lockTime() (uint32, error) {
+	if p.Version == 0 {
+		return p.LockTime, nil
+	}
+	var height, time uint32
+	heightOK, timeOK, set := true, true, false
+	for _, in := range p.Inputs {
+		if in.RequiredHeightLock == 0 && in.RequiredTimeLock == 0 {
+			continue
+		}
+		set = true
+		heightOK = heightOK && in.RequiredHeightLock != 0
+		timeOK = timeOK && in.RequiredTimeLock != 0
+		height = max(height, in.RequiredHeightLock)
+		time = max(time, in.RequiredTimeLock)
+	}
+	switch {
+	case !set:
+		return p.LockTime, nil
+	case heightOK:
+		return height, nil
+	case timeOK:
+		return time, nil
+	}
+	return 0, InvPSBT
+}
+
+// ParsePSBTBase64 parses the base64 encoded PSBT s.
+func ParsePSBTBase64(s string) (*PSBT, error) {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, InvPSBT
+	}
+	return ParsePSBT(b)
+}
+
+// ParsePSBT parses the binary PSBT b of version 0 or 2.
+func ParsePSBT(b []byte) (*PSBT, error) {
+	if !bytes.HasPrefix(b, psbtMagic) {
+		return nil, InvPSBT
+	}
+	r := &txReader{b: b[len(psbtMagic):]}
+	p := new(PSBT)
+	nIn, nOut, ok := p.parseGlobal(r.psbtMap())
+	for i := 0; ok && r.err == nil && i < nIn; i++ {
+		in := new(PSBTInput)
+		if p.Version == 0 {
+			in.PrevOut, in.Sequence = p.Inputs[i].PrevOut, p.Inputs[i].Sequence
+			p.Inputs[i] = in
+		} else {
+			p.Inputs = append(p.Inputs, in)
+		}
+		ok = in.parse(r.psbtMap(), p.Version)
+	}
+	for i := 0; ok && r.err == nil && i < nOut; i++ {
+		out := new(PSBTOutput)
+		if p.Version == 0 {
+			out.Value, out.PkScript = p.Outputs[i].Value, p.Outputs[i].PkScript
+			p.Outputs[i] = out
+		} else {
+			p.Outputs = append(p.Outputs, out)
+		}
+		ok = out.parse(r.psbtMap(), p.Version)
+	}
+	if !ok || r.err != nil || len(r.b) != 0 {
+		return nil, InvPSBT
+	}
+	if _, err := p.lockTime(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// psbtMap reads the PSBT map (the key-value pairs up to the separator), the duplicate keys are not accepted.
+func (r *txReader) psbtMap() []PSBTKV {
+	var m []PSBTKV
+	seen := map[string]bool{}
+	for r.err == nil {
+		k := r.varBytes()
+		if len(k) == 0 {
+			break
+		}
+		if seen[string(k)] {
+			r.err = InvPSBT
+			break
+		}
+		seen[string(k)] = true
+		m = append(m, PSBTKV{k, r.varBytes()})
+	}
+	return m
+}
+
+// psbtKey returns the key type and the key data of the PSBT key k.
+func psbtKey(k []byte) (uint64, []byte) {
+	r := &txReader{b: k}
+	t := r.varInt()
+	if r.err != nil {
+		return 0xffffffff, nil
+	}
+	return t, r.b
+}
+
+// psbtFields calls f for each pair of m with the reader of its value. f returns false for the unknown pairs.
+// The key-only types of keyOnly must not have the key data. Returns false if the value is not read completely.
+func psbtFields(m []PSBTKV, keyOnly []uint64, f func(t uint64, kd []byte, v *txReader) bool, unknown *[]PSBTKV) bool {
+	for _, kv := range m {
+		t, kd := psbtKey(kv.Key)
+		v := &txReader{b: kv.Value}
+		if !f(t, kd, v) {
+			*unknown = append(*unknown, kv)
+			continue
+		}
+		if v.err != nil || len(v.b) != 0 || (len(kd) != 0 && slices.Contains(keyOnly, t)) {
+			return false
+		}
+	}
+	return true
+}
+
+// psbtCheck is used by the psbtFields callbacks for the pairs kept in Unknown (or not allowed with the key data
+// in version 0): returns false if ok (the pair is unknown), otherwise sets the error of v and returns true.
+func psbtCheck(v *txReader, ok bool) bool {
+	if !ok {
+		v.err = InvPSBT
+	}
+	return !ok
+}
+
+// psbtPubKey returns true if kd is a valid compressed or uncompressed public key.
+func psbtPubKey(kd []byte) bool {
+	_, err := ParsePubKey(kd)
+	return err == nil && (len(kd) == 33 || len(kd) == 65)
+}
+
+// psbtKeyOrigin returns true if v is a key origin: the master key fingerprint and the derivation path.
+func psbtKeyOrigin(v []byte) bool {
+	return len(v) >= 4 && len(v)%4 == 0
+}
+
+// psbtTapKeyOrigin returns true if v is the leaf hashes of a taproot key followed by the key origin.
+func psbtTapKeyOrigin(v []byte) bool {
+	r := &txReader{b: v}
+	n := r.varInt()
+	return r.err == nil && n <= uint64(len(r.b))/32 && psbtKeyOrigin(r.b[n*32:])
+}
+
+// parseGlobal parses the global map m, returns the numbers of the inputs and outputs.
+func (p *PSBT) parseGlobal(m []PSBTKV) (int, int, bool) {
+	var tx *Tx
+	var nIn, nOut uint64
+	var version, txVersion, inCount, outCount, lt, modif bool
+	ok := psbtFields(m, []uint64{psbtGlobalUnsignedTx, psbtGlobalTxVersion, psbtGlobalFallbackLockTime,
+		psbtGlobalInputCount, psbtGlobalOutputCount, psbtGlobalTxModifiable, psbtGlobalVersion},
+		func(t uint64, kd []byte, v *txReader) bool {
+			switch t {
+			case psbtGlobalUnsignedTx:
+				var err error
+				if tx, err = parseTx(v.b, false); err != nil {
+					v.err = err
+				}
+				v.b = nil
+			case psbtGlobalTxVersion:
+				p.TxVersion, txVersion = int32(v.u32()), true
+			case psbtGlobalFallbackLockTime:
+				p.LockTime, lt = v.u32(), true
+			case psbtGlobalInputCount:
+				nIn, inCount = v.varInt(), true
+			case psbtGlobalOutputCount:
+				nOut, outCount = v.varInt(), true
+			case psbtGlobalTxModifiable:
+				if m := v.bytes(1); m != nil {
+					p.TxModifiable, modif = m[0], true
+				}
+			case psbtGlobalVersion:
+				p.Version, version = v.u32(), true
+			case psbtGlobalXpub:
+				return psbtCheck(v, len(kd) == 78 && psbtKeyOrigin(v.b))
+			default:
+				return false
+			}
+			return true
+		}, &p.Unknown)
+	if !ok {
+		return 0, 0, false
+	}
+	switch {
+	case p.Version == 0 && tx != nil && !txVersion && !lt && !inCount && !outCount && !modif:
+		for _, in := range tx.TxIn {
+			if len(in.ScriptSig) != 0 {
+				return 0, 0, false
+			}
+		}
+		np, _ := NewPSBT(tx)
+		p.TxVersion, p.LockTime, p.Inputs, p.Outputs = np.TxVersion, np.LockTime, np.Inputs, np.Outputs
+		return len(p.Inputs), len(p.Outputs), true
+	case p.Version == 2 && tx == nil && txVersion && inCount && outCount:
+		return int(min(nIn, 1<<20)), int(min(nOut, 1<<20)), version
+	}
+	return 0, 0, false
+}
+
+func (in *PSBTInput) parse(m []PSBTKV, ver uint32) bool {
+	var txid, index bool
+	if ver != 0 {
+		in.Sequence = 0xffffffff
+	}
+	ok := psbtFields(m, []uint64{psbtInNonWitnessUTXO, psbtInWitnessUTXO, psbtInSigHashType, psbtInRedeemScript,
+		psbtInWitnessScript, psbtInFinalScriptSig, psbtInFinalScriptWitness, psbtInPrevTxID, psbtInOutputIndex,
+		psbtInSequence, psbtInRequiredTimeLock, psbtInRequiredHeightLock, psbtInTapKeySig, psbtInTapInternalKey,
+		psbtInTapMerkleRoot},
+		func(t uint64, kd []byte, v *txReader) bool {
+			if ver == 0 && t >= psbtInPrevTxID && t <= psbtInRequiredHeightLock {
+				return psbtCheck(v, len(kd) != 0)
+			}
+			switch t {
+			case psbtInNonWitnessUTXO:
+				var err error
+				if in.NonWitnessUTXO, err = ParseTx(v.b); err != nil {
+					v.err = err
+				}
+				v.b = nil
+			case psbtInWitnessUTXO:
+				in.WitnessUTXO = &TxOut{Value: int64(v.u64()), PkScript: v.varBytes()}
+			case psbtInPartialSig:
+				if _, err := ParsePubKey(kd); err != nil || (len(kd) != 33 && len(kd) != 65) {
+					v.err = InvPSBT
+				}
+				in.PartialSigs = append(in.PartialSigs, PartialSig{PubKey: kd, Sig: v.b})
+				v.b = nil
+			case psbtInSigHashType:
+				ht := SigHashType(v.u32())
+				in.SigHash = &ht
+			case psbtInRedeemScript:
+				in.RedeemScript, v.b = v.b, nil
+			case psbtInWitnessScript:
+				in.WitnessScript, v.b = v.b, nil
+			case psbtInFinalScriptSig:
+				in.FinalScriptSig, v.b = v.b, nil
+			case psbtInFinalScriptWitness:
+				in.FinalScriptWitness = v.witness()
+			case psbtInPrevTxID:
+				in.PrevOut.Hash, txid = [32]byte(v.bytes(32)), true
+			case psbtInOutputIndex:
+				in.PrevOut.Index, index = v.u32(), true
+			case psbtInSequence:
+				in.Sequence = v.u32()
+			case psbtInRequiredTimeLock:
+				if in.RequiredTimeLock = v.u32(); in.RequiredTimeLock < 500000000 {
+					v.err = InvPSBT
+				}
+			case psbtInRequiredHeightLock:
+				if in.RequiredHeightLock = v.u32(); in.RequiredHeightLock == 0 || in.RequiredHeightLock >= 500000000 {
+					v.err = InvPSBT
+				}
+			case psbtInTapKeySig:
+				if len(v.b) != 64 && len(v.b) != 65 {
+					v.err = InvPSBT
+				}
+				in.TapKeySig, v.b = v.b, nil
+			case psbtInTapInternalKey:
+				in.TapInternalKey = v.bytes(32)
+			case psbtInTapMerkleRoot:
+				in.TapMerkleRoot = v.bytes(32)
+			case psbtInBIP32Derivation:
+				return psbtCheck(v, psbtPubKey(kd) && psbtKeyOrigin(v.b))
+			case psbtInTapScriptSig:
+				return psbtCheck(v, len(kd) == 64 && (len(v.b) == 64 || len(v.b) == 65))
+			case psbtInTapLeafScript:
+				return psbtCheck(v, len(kd) >= 33 && (len(kd)-33)%32 == 0 && len(kd) <= 33+128*32 && len(v.b) >= 1)
+			case psbtInTapBIP32Derivation:
+				return psbtCheck(v, len(kd) == 32 && psbtTapKeyOrigin(v.b))
+			default:
+				return false
+			}
+			return true
+		}, &in.Unknown)
+	if !ok || (ver == 2 && (!txid || !index)) {
+		return false
+	}
+	u := in.NonWitnessUTXO
+	return u == nil || (u.TxHash() == in.PrevOut.Hash && int(in.PrevOut.Index) < len(u.TxOut))
+}
+
+func (out *PSBTOutput) parse(m []PSBTKV, ver uint32) bool {
+	var amount, script bool
+	ok := psbtFields(m, []uint64{psbtOutRedeemScript, psbtOutWitnessScript, psbtOutAmount, psbtOutScript,
+		psbtOutTapInternalKey},
+		func(t uint64, kd []byte, v *txReader) bool {
+			if ver == 0 && (t == psbtOutAmount || t == psbtOutScript) {
+				return psbtCheck(v, len(kd) != 0)
+			}
+			switch t {
+			case psbtOutRedeemScript:
+				out.RedeemScript, v.b = v.b, nil
+			case psbtOutWitnessScript:
+				out.WitnessScript, v.b = v.b, nil
+			case psbtOutAmount:
+				out.Value, amount = int64(v.u64()), true
+			case psbtOutScript:
+				out.PkScript, script, v.b = v.b, true, nil
+			case psbtOutTapInternalKey:
+				out.TapInternalKey = v.bytes(32)
+			case psbtOutBIP32Derivation:
+				return psbtCheck(v, psbtPubKey(kd) && psbtKeyOrigin(v.b))
+			case psbtOutTapBIP32Derivation:
+				return psbtCheck(v, len(kd) == 32 && psbtTapKeyOrigin(v.b))
+			default:
+				return false
+			}
+			return true
+		}, &out.Unknown)
+	return ok && (ver == 0 || (amount && script))
+}
+
+// Serialize returns the binary encoding of p.
+func (p *PSBT) Serialize() []byte {
+	var m []PSBTKV
+	if p.Version == 0 {
+		tx, _ := p.UnsignedTx()
+		m = append(m, psbtKV(psbtGlobalUnsignedTx, nil, tx.SerializeNoWitness()))
+	} else {
+		m = append(m, psbtKV(psbtGlobalTxVersion, nil, le32(uint32(p.TxVersion))))
+		if p.LockTime != 0 {
+			m = append(m, psbtKV(psbtGlobalFallbackLockTime, nil, le32(p.LockTime)))
+		}
+		m = append(m, psbtKV(psbtGlobalInputCount, nil, appendVarInt(nil, uint64(len(p.Inputs)))))
+		m = append(m, psbtKV(psbtGlobalOutputCount, nil, appendVarInt(nil, uint64(len(p.Outputs)))))
+		if p.TxModifiable != 0 {
+			m = append(m, psbtKV(psbtGlobalTxModifiable, nil, []byte{p.TxModifiable}))
+		}
+		m = append(m, psbtKV(psbtGlobalVersion, nil, le32(p.Version)))
+	}
+	b := appendPSBTMap(slices.Clone(psbtMagic), append(m, p.Unknown...))
+	for _, in := range p.Inputs {
+		b = in.append(b, p.Version)
+	}
+	for _, out := range p.Outputs {
+		b = out.append(b, p.Version)
+	}
+	return b
+}
+
+// Base64 returns the base64 encoding of p.
+func (p *PSBT) Base64() string {
+	return base64.StdEncoding.EncodeToString(p.Serialize())
+}
+
+func (in *PSBTInput) append(b []byte, ver uint32) []byte {
+	var m []PSBTKV
+	if in.NonWitnessUTXO != nil {
+		m = append(m, psbtKV(psbtInNonWitnessUTXO, nil, in.NonWitnessUTXO.Serialize()))
+	}
+	if in.WitnessUTXO != nil {
+		m = append(m, psbtKV(psbtInWitnessUTXO, nil, in.WitnessUTXO.append(nil)))
+	}
+	for _, ps := range in.PartialSigs {
+		m = append(m, psbtKV(psbtInPartialSig, ps.PubKey, ps.Sig))
+	}
+	if in.SigHash != nil {
+		m = append(m, psbtKV(psbtInSigHashType, nil, le32(uint32(*in.SigHash))))
+	}
+	if in.RedeemScript != nil {
+		m = append(m, psbtKV(psbtInRedeemScript, nil, in.RedeemScript))
+	}
+	if in.WitnessScript != nil {
+		m = append(m, psbtKV(psbtInWitnessScript, nil, in.WitnessScript))
+	}
+	if in.FinalScriptSig != nil {
+		m = append(m, psbtKV(psbtInFinalScriptSig, nil, in.FinalScriptSig))
+	}
+	if in.FinalScriptWitness != nil {
+		m = append(m, psbtKV(psbtInFinalScriptWitness, nil, appendWitness(nil, in.FinalScriptWitness)))
+	}
+	if ver != 0 {
+		m = append(m, psbtKV(psbtInPrevTxID, nil, in.PrevOut.Hash[:]))
+		m = append(m, psbtKV(psbtInOutputIndex, nil, le32(in.PrevOut.Index)))
+		if in.Sequence != 0xffffffff {
+			m = append(m, psbtKV(psbtInSequence, nil, le32(in.Sequence)))
+		}
+		if in.RequiredTimeLock != 0 {
+			m = append(m, psbtKV(psbtInRequiredTimeLock, nil, le32(in.RequiredTimeLock)))
+		}
+		if in.RequiredHeightLock != 0 {
+			m = append(m, psbtKV(psbtInRequiredHeightLock, nil, le32(in.RequiredHeightLock)))
+		}
+	}
+	if in.TapKeySig != nil {
+		m = append(m, psbtKV(psbtInTapKeySig, nil, in.TapKeySig))
+	}
+	if in.TapInternalKey != nil {
+		m = append(m, psbtKV(psbtInTapInternalKey, nil, in.TapInternalKey))
+	}
+	if in.TapMerkleRoot != nil {
+		m = append(m, psbtKV(psbtInTapMerkleRoot, nil, in.TapMerkleRoot))
+	}
+	return appendPSBTMap(b, append(m, in.Unknown...))
+}
+
+func (out *PSBTOutput) append(b []byte, ver uint32) []byte {
+	var m []PSBTKV
+	if out.RedeemScript != nil {
+		m = append(m, psbtKV(psbtOutRedeemScript, nil, out.RedeemScript))
+	}
+	if out.WitnessScript != nil {
+		m = append(m, psbtKV(psbtOutWitnessScript, nil, out.WitnessScript))
+	}
+	if ver != 0 {
+		m = append(m, psbtKV(psbtOutAmount, nil, binary.LittleEndian.AppendUint64(nil, uint64(out.Value))))
+		m = append(m, psbtKV(psbtOutScript, nil, out.PkScript))
+	}
+	if out.TapInternalKey != nil {
+		m = append(m, psbtKV(psbtOutTapInternalKey, nil, out.TapInternalKey))
+	}
+	return appendPSBTMap(b, append(m, out.Unknown...))
+}
+
+// psbtKV returns the pair with the key type t, the key data kd and the value v.
+func psbtKV(t uint64, kd, v []byte) PSBTKV {
+	return PSBTKV{append(appendVarInt(nil, t), kd...), v}
+}
+
+// appendPSBTMap appends the pairs m ordered by the key type (the order of the pairs of the same type is kept)
+// and the map separator to b. m is sorted in place.
+func appendPSBTMap(b []byte, m []PSBTKV) []byte {
+	slices.SortStableFunc(m, func(x, y PSBTKV) int {
+		tx, _ := psbtKey(x.Key)
+		ty, _ := psbtKey(y.Key)
+		return cmp.Compare(tx, ty)
+	})
+	for _, kv := range m {
+		b = appendVarBytes(appendVarBytes(b, kv.Key), kv.Value)
+	}
+	return append(b, 0x00)
+}
+
+func le32(v uint32) []byte {
+	return binary.LittleEndian.AppendUint32(nil, v)
+}
+
+// spent returns the output spent by the input i or nil if it is unknown. NonWitnessUTXO is used if set
+// (nil is returned if it is not the previous transaction of the input), otherwise WitnessUTXO.
+func (p *PSBT) spent(i int) *TxOut {
+	in := p.Inputs[i]
+	if u := in.NonWitnessUTXO; u != nil {
+		if int(in.PrevOut.Index) < len(u.TxOut) && u.TxHash() == in.PrevOut.Hash {
+			return u.TxOut[in.PrevOut.Index]
+		}
+		return nil
+	}
+	return in.WitnessUTXO
+}
+
+// IsFinal returns true if the input has the final scriptSig or witness.
+func (in *PSBTInput) IsFinal() bool {
+	return in.FinalScriptSig != nil || in.FinalScriptWitness != nil
+}
+
+// keyInput returns the type of the output spent by the not finalized input i and the public key if it
+// can be spent by the key with the compressed public key comp and the uncompressed public key uncomp.
+func (p *PSBT) keyInput(i int, comp, uncomp []byte) (AddressType, []byte, bool) {
+	spent := p.spent(i)
+	if spent == nil || p.Inputs[i].IsFinal() {
+		return 0, nil, false
+	}
+	s := spent.PkScript
+	h := HashPubKey(comp)
+	switch {
+	case bytes.Equal(s, p2pkhScript(h)):
+		return P2PKH, comp, true
+	case bytes.Equal(s, p2pkhScript(HashPubKey(uncomp))):
+		return P2PKHUncomp, uncomp, true
+	case bytes.Equal(s, p2shScript(HashPubKey(witnessScript(0, h)))):
+		return P2SHP2WPKH, comp, true
+	case bytes.Equal(s, witnessScript(0, h)):
+		return P2WPKH, comp, true
+	}
+	if q, err := TapOutputKey(comp, p.Inputs[i].TapMerkleRoot); err == nil && bytes.Equal(s, witnessScript(1, q)) {
+		return P2TR, comp, true
+	}
+	return 0, nil, false
+}
+
+// SpendableBy returns the indexes of the not finalized inputs of p which can be signed by k (see SignPSBT).
+// The P2PKH inputs without NonWitnessUTXO are not included.
+func (p *PSBT) SpendableBy(k *PrKey) []int {
+	if k.isSet() != nil {
+		return nil
+	}
+	comp, uncomp := PubKey(&k.k, false), PubKey(&k.k, true)
+	var res []int
+	for i := range p.Inputs {
+		if at, _, ok := p.keyInput(i, comp, uncomp); ok && (p.Inputs[i].NonWitnessUTXO != nil || at != P2PKH && at != P2PKHUncomp) {
+			res = append(res, i)
+		}
+	}
+	return res
+}
+
+// SignPSBT signs the not finalized inputs of p spending P2PKH, P2SH-P2WPKH, P2WPKH or P2TR (key path, with
+// TapMerkleRoot of the input if set) outputs of k: adds the partial signatures or sets TapKeySig.
+// The previous output (WitnessUTXO or NonWitnessUTXO) of the input must be set, P2PKH inputs need
+// NonWitnessUTXO (PSBTNoUTXO is returned otherwise), P2TR needs the previous outputs of all the inputs
+// unless SigHashAnyOneCanPay is used. The sighash type of the input is used if set,
+// otherwise SigHashAll (SigHashDefault for P2TR). Returns the number of the signed inputs.
+// All the inputs are signed before p is changed, so p is not changed if an error is returned.
+func (k *PrKey) SignPSBT(p *PSBT) (int, error) {
+	if err := k.isSet(); err != nil {
+		return 0, err
+	}
+	tx, err := p.UnsignedTx()
+	if err != nil {
+		return 0, err
+	}
+	comp, uncomp := PubKey(&k.k, false), PubKey(&k.k, true)
+	prevOuts := make([]*TxOut, len(p.Inputs))
+	for i := range p.Inputs {
+		prevOuts[i] = p.spent(i)
+	}
+	types := make([]AddressType, len(p.Inputs))
+	pubs, sigs := make([][]byte, len(p.Inputs)), make([][]byte, len(p.Inputs))
+	for i, in := range p.Inputs {
+		at, pub, ok := p.keyInput(i, comp, uncomp)
+		if !ok {
+			continue
+		}
+		if (at == P2PKH || at == P2PKHUncomp) && in.NonWitnessUTXO == nil {
+			return 0, PSBTNoUTXO
+		}
+		if at == P2TR {
+			ht := SigHashDefault
+			if in.SigHash != nil {
+				ht = *in.SigHash
+			}
+			if !ht.anyOneCanPay() && slices.Contains(prevOuts, nil) {
+				return 0, PSBTNoUTXO
+			}
+			h, err := tx.SigHashTaproot(i, prevOuts, ht, nil)
+			if err != nil {
+				return 0, err
+			}
+			sig, err := k.SignTaproot(h, nil, in.TapMerkleRoot)
+			if err != nil {
+				return 0, err
+			}
+			if ht != SigHashDefault {
+				sig = append(sig, byte(ht))
+			}
+			types[i], pubs[i], sigs[i] = at, pub, sig
+			continue
+		}
+		ht := SigHashAll
+		if in.SigHash != nil {
+			ht = *in.SigHash
+		}
+		if ht > 0xff || ht.base() < SigHashAll || ht.base() > SigHashSingle {
+			return 0, InvSigHashType
+		}
+		var h []byte
+		switch at {
+		case P2PKH, P2PKHUncomp:
+			h, err = tx.SigHashLegacy(i, prevOuts[i].PkScript, ht)
+		case P2SHP2WPKH, P2WPKH:
+			h, err = tx.SigHashWitnessV0(i, p2pkhScript(HashPubKey(pub)), prevOuts[i].Value, ht)
+		}
+		if err != nil {
+			return 0, err
+		}
+		sig, err := k.Sign(h)
+		if err != nil {
+			return 0, err
+		}
+		types[i], pubs[i], sigs[i] = at, pub, append(sig.DER(), byte(ht))
+	}
+	n := 0
+	for i, in := range p.Inputs {
+		if sigs[i] == nil {
+			continue
+		}
+		switch types[i] {
+		case P2TR:
+			in.TapKeySig = sigs[i]
+			if in.TapInternalKey == nil {
+				in.TapInternalKey = comp[1:]
+			}
+		case P2SHP2WPKH:
+			in.RedeemScript, _ = RedeemScriptP2SHP2WPKH(pubs[i])
+			fallthrough
+		default:
+			in.setPartialSig(pubs[i], sigs[i])
+		}
+		n++
+	}
+	return n, nil
+}
+
+// setPartialSig adds or replaces the partial signature for pub.
+func (in *PSBTInput) setPartialSig(pub, sig []byte) {
+	for i, ps := range in.PartialSigs {
+		if bytes.Equal(ps.PubKey, pub) {
+			in.PartialSigs[i].Sig = sig
+			return
+		}
+	}
+	in.PartialSigs = append(in.PartialSigs, PartialSig{PubKey: pub, Sig: sig})
+}
+
+// Finalize finalizes the signed inputs of p (see SignPSBT): sets the final scriptSig and witness and removes
+// the other data of the input except the previous outputs and the proprietary or unknown pairs.
+// The signatures are not verified. Returns PSBTNotFinal if some inputs cannot be finalized.
+func (p *PSBT) Finalize() error {
+	final := true
+	for i, in := range p.Inputs {
+		if !in.IsFinal() && !p.finalizeInput(i) {
+			final = false
+		}
+	}
+	if !final {
+		return PSBTNotFinal
+	}
+	return nil
+}
+
+func (p *PSBT) finalizeInput(i int) bool {
+	in, spent := p.Inputs[i], p.spent(i)
+	if spent == nil {
+		return false
+	}
+	s := spent.PkScript
+	if len(s) == 34 && s[0] == 0x51 && s[1] == 0x20 {
+		if in.TapKeySig == nil {
+			return false
+		}
+		in.FinalScriptWitness = [][]byte{in.TapKeySig}
+	} else {
+		for _, ps := range in.PartialSigs {
+			h := HashPubKey(ps.PubKey)
+			switch {
+			case bytes.Equal(s, p2pkhScript(h)):
+				in.FinalScriptSig = appendPush(appendPush(nil, ps.Sig), ps.PubKey)
+			case bytes.Equal(s, witnessScript(0, h)):
+				in.FinalScriptWitness = [][]byte{ps.Sig, ps.PubKey}
+			case bytes.Equal(s, p2shScript(HashPubKey(witnessScript(0, h)))):
+				in.FinalScriptSig = appendPush(nil, witnessScript(0, h))
+				in.FinalScriptWitness = [][]byte{ps.Sig, ps.PubKey}
+			default:
+				continue
+			}
+			break
+		}
+		if !in.IsFinal() {
+			return false
+		}
+	}
+	in.PartialSigs, in.SigHash, in.RedeemScript, in.WitnessScript = nil, nil, nil, nil
+	in.TapKeySig, in.TapInternalKey, in.TapMerkleRoot = nil, nil, nil
+	in.Unknown = slices.DeleteFunc(in.Unknown, func(kv PSBTKV) bool {
+		t, _ := psbtKey(kv.Key)
+		return t == psbtInBIP32Derivation || (t >= psbtInTapScriptSig && t <= psbtInTapBIP32Derivation)
+	})
+	return true
+}
+
+// Extract returns the signed network transaction of the finalized p. Returns PSBTNotFinal if some inputs
+// are not finalized.
+func (p *PSBT) Extract() (*Tx, error) {
+	tx, err := p.UnsignedTx()
+	if err != nil {
+		return nil, err
+	}
+	for i, in := range p.Inputs {
+		if !in.IsFinal() {
+			return nil, PSBTNotFinal
+		}
+		tx.TxIn[i].ScriptSig, tx.TxIn[i].Witness = in.FinalScriptSig, in.FinalScriptWitness
+	}
+	return tx, nil
+}
@@ -0,0 +1,350 @@
+package cckat
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// bip174Vectors are the BIP174 and BIP371 test vectors (base64).
+type bip174Vectors struct {
+	Valid   []string `json:"valid"`
+	Invalid []struct {
+		Comment string `json:"comment"`
+		PSBT    string `json:"psbt"`
+	} `json:"invalid"`
+}
+
+func readBIP174Vectors(t *testing.T) *bip174Vectors {
+	t.Helper()
+	b, err := os.ReadFile("testdata/bip174-vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := new(bip174Vectors)
+	if err := json.Unmarshal(b, v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestPSBTVectors(t *testing.T) {
+	v := readBIP174Vectors(t)
+	for i, s := range v.Valid {
+		p, err := ParsePSBTBase64(s)
+		if err != nil {
+			t.Errorf("valid %d: %v", i, err)
+			continue
+		}
+		if p.Base64() != s {
+			t.Errorf("valid %d: round trip\ngot  %s\nwant %s", i, p.Base64(), s)
+		}
+	}
+	for _, c := range v.Invalid {
+		if _, err := ParsePSBTBase64(c.PSBT); err == nil {
+			t.Errorf("invalid %q: parsed", c.Comment)
+		}
+	}
+}
+
+func TestSignPSBT(t *testing.T) {
+	k := testKey(t, "0000000000000000000000000000000000000000000000000000000000000001")
+	pub := k.PubK().Compressed()
+	p2pkh, _ := PkScript(pub, P2PKH)
+	p2wpkh, _ := PkScript(pub, P2WPKH)
+	prev := &Tx{
+		Version: 2,
+		TxIn:    []*TxIn{{PrevOut: OutPoint{Hash: [32]byte{1}}, Sequence: 0xffffffff}},
+		TxOut:   []*TxOut{{Value: 100000, PkScript: p2pkh}, {Value: 200000, PkScript: p2wpkh}},
+	}
+	h := prev.TxHash()
+	tx := &Tx{
+		Version: 2,
+		TxIn:    []*TxIn{{PrevOut: OutPoint{h, 0}, Sequence: 0xffffffff}, {PrevOut: OutPoint{h, 1}, Sequence: 0xffffffff}},
+		TxOut:   []*TxOut{{Value: 290000, PkScript: p2wpkh}},
+	}
+	p, err := NewPSBT(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// P2PKH needs the previous transaction
+	p.Inputs[0].WitnessUTXO, p.Inputs[1].WitnessUTXO = prev.TxOut[0], prev.TxOut[1]
+	if s := p.SpendableBy(k); len(s) != 1 || s[0] != 1 {
+		t.Errorf("SpendableBy without NonWitnessUTXO: %v", s)
+	}
+	if _, err := k.SignPSBT(p); err != PSBTNoUTXO {
+		t.Errorf("SignPSBT without NonWitnessUTXO: %v", err)
+	}
+	if p.Inputs[1].PartialSigs != nil {
+		t.Errorf("input 1 signed by the failed SignPSBT")
+	}
+
+	// NonWitnessUTXO of another transaction
+	other := *prev
+	other.LockTime = 1
+	p.Inputs[0].NonWitnessUTXO, p.Inputs[0].WitnessUTXO = &other, nil
+	if s := p.SpendableBy(k); len(s) != 1 || s[0] != 1 {
+		t.Errorf("SpendableBy with the wrong NonWitnessUTXO: %v", s)
+	}
+
+	p.Inputs[0].NonWitnessUTXO = prev
+	if s := p.SpendableBy(k); len(s) != 2 {
+		t.Errorf("SpendableBy: %v", s)
+	}
+	if n, err := k.SignPSBT(p); n != 2 || err != nil {
+		t.Fatalf("SignPSBT: %d, %v", n, err)
+	}
+	s0, s1 := p.Inputs[0].PartialSigs[0].Sig, p.Inputs[1].PartialSigs[0].Sig
+	h0, _ := tx.SigHashLegacy(0, p2pkh, SigHashAll)
+	h1, _ := tx.SigHashWitnessV0(1, p2pkhScript(HashPubKey(pub)), 200000, SigHashAll)
+	for i, c := range [][2][]byte{{s0, h0}, {s1, h1}} {
+		sig, err := ParseDER(c[0][:len(c[0])-1])
+		if err != nil || !VerifySignature(pub, c[1], sig) {
+			t.Errorf("input %d: invalid signature %x, %v", i, c[0], err)
+		}
+	}
+	if q, err := ParsePSBTBase64(p.Base64()); err != nil || q.Base64() != p.Base64() {
+		t.Errorf("round trip of the signed PSBT: %v", err)
+	}
+	if err := p.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+	signed, err := p.Extract()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signed.TxIn[0].ScriptSig == nil || len(signed.TxIn[1].Witness) != 2 {
+		t.Errorf("extracted transaction %x", signed.Serialize())
+	}
+}
+
+// TestSignPSBTTypes spends P2PKH, P2SH-P2WPKH, P2WPKH and P2TR outputs, the expected transaction
+// is signed by btcd and verified by its script engine.
+func TestSignPSBTTypes(t *testing.T) {
+	const (
+		prevHex = "020000000101000000000000000000000000000000000000000000000000000000000000000000000000ffffffff04" +
+			"a0860100000000001976a9147ab29539577f962f7370c67f5e8e81ee0bd72c1688ac" +
+			"400d03000000000017a914ae7469b2b5ed1f53392a57a6e6aea5e7336b103287" +
+			"e0930400000000001600147ab29539577f962f7370c67f5e8e81ee0bd72c16" +
+			"801a06000000000022512016d1a81455b41f9c49a997d4478c43c97276341aea99790d2a841deb4ca78b0300000000"
+		signedHex = "02000000000104" +
+			"f50f38f6430677094545effdbd9c6e17804d15c48565dfbd430e773566fb48ff000000006a" +
+			"47304402206053d7ee01a8c2c3d1760b3481171365fffefec29b7f82b10618a8675def72780220188167a5e81a24ced197c56e2e932cdedf30ac532d1e64acc1212acdba3c876401" +
+			"2103b8a5ffc85dfe7494777bcb1ae4790c9a0083e6ab0eb2faafe383f71ec9531c4dffffffff" +
+			"f50f38f6430677094545effdbd9c6e17804d15c48565dfbd430e773566fb48ff01000000171600147ab29539577f962f7370c67f5e8e81ee0bd72c16ffffffff" +
+			"f50f38f6430677094545effdbd9c6e17804d15c48565dfbd430e773566fb48ff0200000000ffffffff" +
+			"f50f38f6430677094545effdbd9c6e17804d15c48565dfbd430e773566fb48ff0300000000ffffffff" +
+			"01301b0f00000000001600147ab29539577f962f7370c67f5e8e81ee0bd72c16" +
+			"00" +
+			"02483045022100d6990f1635736e129fd2877ebb17de440d2b34251c6bb0359e1002b7f6310e4a022040016f504f94163a2fbf426ac33bdd19ae5c2c96d6c473578f842247af2e1da001" +
+			"2103b8a5ffc85dfe7494777bcb1ae4790c9a0083e6ab0eb2faafe383f71ec9531c4d" +
+			"02473044022051f4107f25afb0b2ce1166e2a61412dcc55af4a85d434c9fab73fc344a0952fb022049400125b1e26e0aab5d9bc7b674c284175fd4f7b1d7fc2f36335c724f1c0b4401" +
+			"2103b8a5ffc85dfe7494777bcb1ae4790c9a0083e6ab0eb2faafe383f71ec9531c4d" +
+			"0140" + "0cb722699dee3bfe3170b8274f8a806567e9d03c9bf872d410f16c4b82473ed2af8da4c64d5a73a997fda5a405afbdb2d33ed52ea95da4d392f4b51310793152" +
+			"00000000"
+		tapSigHash = "bba188ab7a044d0c09098bd7aa894eb2c8db4d7a399fbea20703adfb92b02f10"
+	)
+	k := testKey(t, "dc38b9569c7a79acf8d8878f0dfd32579b165d92bd41c387d4bad38fdbdcf955")
+	prev, err := ParseTxHex(prevHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := ParseTxHex(signedHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := &Tx{Version: 2, TxOut: want.TxOut}
+	for _, in := range want.TxIn {
+		tx.TxIn = append(tx.TxIn, &TxIn{PrevOut: in.PrevOut, Sequence: in.Sequence})
+	}
+	p, err := NewPSBT(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Inputs[0].NonWitnessUTXO = prev
+	for i := 1; i < 4; i++ {
+		p.Inputs[i].WitnessUTXO = prev.TxOut[i]
+	}
+	if s := p.SpendableBy(k); len(s) != 4 {
+		t.Errorf("SpendableBy: %v", s)
+	}
+	if n, err := k.SignPSBT(p); n != 4 || err != nil {
+		t.Fatalf("SignPSBT: %d, %v", n, err)
+	}
+	if rs := hex.EncodeToString(p.Inputs[1].RedeemScript); rs != "00147ab29539577f962f7370c67f5e8e81ee0bd72c16" {
+		t.Errorf("P2SH-P2WPKH redeem script %s", rs)
+	}
+	if q, err := ParsePSBTBase64(p.Base64()); err != nil || q.Base64() != p.Base64() {
+		t.Errorf("round trip of the signed PSBT: %v", err)
+	}
+	if err := p.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+	signed, err := p.Extract()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the taproot signature uses random auxiliary data
+	w := signed.TxIn[3].Witness
+	if len(w) != 1 || !VerifySchnorr(prev.TxOut[3].PkScript[2:], mustHex(t, tapSigHash), w[0]) {
+		t.Errorf("P2TR witness %x", w)
+	}
+	signed.TxIn[3].Witness = want.TxIn[3].Witness
+	if signed.Hex() != signedHex {
+		t.Errorf("signed transaction\ngot  %s\nwant %s", signed.Hex(), signedHex)
+	}
+	if signed.TxID() != "92ad64c3d2be98139d9076fda9f481cc6ccfd8caab587cece88ffdc0797d31cc" {
+		t.Errorf("txid %s", signed.TxID())
+	}
+}
+
+// The parts of the BIP370 test vectors: the PSBTs of the spending of the output 0 of prevTx.
+const (
+	bip370PrevTx = "0200000001c1aa256e214b96a1822f93de42bff3b5f3ff8d0519306e3515d7515a5e805b120000000000ffffffff" +
+		"0118c69a3b00000000160014b0a3af144208412693ca7d166852b52db0aef06e00000000"
+	bip370PrevTxID = "0b0ad921419c1c8719735d72dc739f9ea9e0638d1fe4c1eef0f9944084815fc8"
+	bip370Amount0  = "0008af2f00000000"
+	bip370Script0  = "0014c430f64c4756da310dbd1a085572ef299926272c"
+	bip370Amount1  = "8bbdeb0b00000000"
+	bip370Script1  = "00144dd193ac964a56ac1b9e1cca8454fe2f474f8513"
+	bip370Tx       = "02000000010b0ad921419c1c8719735d72dc739f9ea9e0638d1fe4c1eef0f9944084815fc80000000000feffffff02" +
+		bip370Amount0 + "16" + bip370Script0 + bip370Amount1 + "16" + bip370Script1 + "00000000"
+)
+
+// buildPSBT returns the PSBT of the maps of the hex encoded key-value pairs.
+func buildPSBT(t *testing.T, maps ...[][2]string) []byte {
+	t.Helper()
+	b := []byte("psbt\xff")
+	for _, m := range maps {
+		for _, kv := range m {
+			b = appendVarBytes(b, mustHex(t, kv[0]))
+			b = appendVarBytes(b, mustHex(t, kv[1]))
+		}
+		b = append(b, 0x00)
+	}
+	return b
+}
+
+// with returns m with the pairs kv added, the pairs of the same keys are replaced.
+// The pairs with the empty value are removed.
+func with(m [][2]string, kv ...[2]string) [][2]string {
+	var res [][2]string
+	for _, p := range m {
+		if !slices.ContainsFunc(kv, func(x [2]string) bool { return x[0] == p[0] }) {
+			res = append(res, p)
+		}
+	}
+	for _, p := range kv {
+		if p[1] != "" {
+			res = append(res, p)
+		}
+	}
+	slices.SortFunc(res, func(x, y [2]string) int { return strings.Compare(x[0], y[0]) })
+	return res
+}
+
+func TestPSBTv2(t *testing.T) {
+	global := [][2]string{{"02", "02000000"}, {"04", "01"}, {"05", "02"}, {"fb", "02000000"}}
+	in := [][2]string{{"0e", bip370PrevTxID}, {"0f", "00000000"}}
+	in1 := [][2]string{{"0e", bip370PrevTxID}, {"0f", "01000000"}}
+	out0 := [][2]string{{"03", bip370Amount0}, {"04", bip370Script0}}
+	out1 := [][2]string{{"03", bip370Amount1}, {"04", bip370Script1}}
+	global2 := with(global, [2]string{"04", "02"})
+	updated := with(in, [2]string{"00", bip370PrevTx},
+		[2]string{"01", "18c69a3b00000000160014b0a3af144208412693ca7d166852b52db0aef06e"}, [2]string{"10", "feffffff"})
+	height, time := [2]string{"12", "10270000"}, [2]string{"11", "8c20c462"} // 10000, 1657020556
+
+	for _, c := range []struct {
+		name     string
+		maps     [][][2]string
+		lockTime uint32
+	}{
+		{"required fields only", [][][2]string{global, in, out0, out1}, 0},
+		{"updated", [][][2]string{global, updated, out0, out1}, 0},
+		{"fallback locktime", [][][2]string{with(global, [2]string{"03", "e8030000"}), updated, out0, out1}, 1000},
+		{"time locktime", [][][2]string{with(global, [2]string{"03", "e8030000"}), with(updated, time), out0, out1}, 1657020556},
+		{"height locktime", [][][2]string{with(global, [2]string{"03", "e8030000"}), with(updated, height), out0, out1}, 10000},
+		{"both locktimes", [][][2]string{global, with(updated, height, time), out0, out1}, 10000},
+		{"tx modifiable", [][][2]string{with(global, [2]string{"06", "03"}), updated, out0, out1}, 0},
+		{"no locktimes", [][][2]string{with(global2, [2]string{"03", "e8030000"}), in, in1, out0, out1}, 1000},
+		{"both and none", [][][2]string{global2, with(in, height, time), in1, out0, out1}, 10000},
+		{"both and height", [][][2]string{global2, with(in, height, time), with(in1, [2]string{"12", "12270000"}), out0, out1}, 10002},
+		{"both and time", [][][2]string{global2, with(in, height, time), with(in1, [2]string{"11", "9020c462"}), out0, out1}, 1657020560},
+	} {
+		b := buildPSBT(t, c.maps...)
+		p, err := ParsePSBT(b)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if s := p.Serialize(); !bytes.Equal(s, b) {
+			t.Errorf("%s: round trip\ngot  %x\nwant %x", c.name, s, b)
+		}
+		tx, err := p.UnsignedTx()
+		if err != nil || tx.LockTime != c.lockTime {
+			t.Errorf("%s: unsigned transaction %v, %v, want locktime %d", c.name, tx, err, c.lockTime)
+		}
+	}
+
+	// the updated PSBT is the version 2 of the PSBT of the unsigned transaction
+	p, err := ParsePSBT(buildPSBT(t, global, updated, out0, out1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx, err := p.UnsignedTx(); err != nil || tx.Hex() != bip370Tx {
+		t.Errorf("unsigned transaction %v, %v", tx, err)
+	}
+	v0 := buildPSBT(t, [][2]string{{"00", bip370Tx}}, with(updated, [2]string{"0e", ""}, [2]string{"0f", ""}, [2]string{"10", ""}), nil, nil)
+	if q, err := ParsePSBT(v0); err != nil || !bytes.Equal(q.Serialize(), v0) {
+		t.Errorf("version 0: %v", err)
+	} else {
+		q.Version = 2
+		if !bytes.Equal(q.Serialize(), p.Serialize()) {
+			t.Errorf("version 0 converted to 2\ngot  %x\nwant %x", q.Serialize(), p.Serialize())
+		}
+	}
+
+	v0Global := [][2]string{{"00", bip370Tx}}
+	for _, c := range []struct {
+		name string
+		maps [][][2]string
+	}{
+		{"v0 with PSBT_GLOBAL_VERSION 2", [][][2]string{with(v0Global, [2]string{"fb", "02000000"}), nil, nil, nil}},
+		{"v0 with PSBT_GLOBAL_TX_VERSION", [][][2]string{with(v0Global, [2]string{"02", "02000000"}), nil, nil, nil}},
+		{"v0 with PSBT_GLOBAL_FALLBACK_LOCKTIME", [][][2]string{with(v0Global, [2]string{"03", "00000000"}), nil, nil, nil}},
+		{"v0 with PSBT_GLOBAL_INPUT_COUNT", [][][2]string{with(v0Global, [2]string{"04", "01"}), nil, nil, nil}},
+		{"v0 with PSBT_GLOBAL_OUTPUT_COUNT", [][][2]string{with(v0Global, [2]string{"05", "02"}), nil, nil, nil}},
+		{"v0 with PSBT_GLOBAL_TX_MODIFIABLE", [][][2]string{with(v0Global, [2]string{"06", "00"}), nil, nil, nil}},
+		{"v0 with PSBT_IN_PREVIOUS_TXID", [][][2]string{v0Global, [][2]string{{"0e", bip370PrevTxID}}, nil, nil}},
+		{"v0 with PSBT_IN_OUTPUT_INDEX", [][][2]string{v0Global, [][2]string{{"0f", "00000000"}}, nil, nil}},
+		{"v0 with PSBT_IN_SEQUENCE", [][][2]string{v0Global, [][2]string{{"10", "feffffff"}}, nil, nil}},
+		{"v0 with PSBT_IN_REQUIRED_TIME_LOCKTIME", [][][2]string{v0Global, [][2]string{time}, nil, nil}},
+		{"v0 with PSBT_IN_REQUIRED_HEIGHT_LOCKTIME", [][][2]string{v0Global, [][2]string{height}, nil, nil}},
+		{"v0 with PSBT_OUT_AMOUNT", [][][2]string{v0Global, nil, [][2]string{{"03", bip370Amount0}}, nil}},
+		{"v0 with PSBT_OUT_SCRIPT", [][][2]string{v0Global, nil, [][2]string{{"04", bip370Script0}}, nil}},
+		{"v2 with PSBT_GLOBAL_UNSIGNED_TX", [][][2]string{with(global, [2]string{"00", bip370Tx}), in, out0, out1}},
+		{"v2 missing PSBT_GLOBAL_TX_VERSION", [][][2]string{with(global, [2]string{"02", ""}), in, out0, out1}},
+		{"v2 missing PSBT_GLOBAL_INPUT_COUNT", [][][2]string{with(global, [2]string{"04", ""}), in, out0, out1}},
+		{"v2 missing PSBT_GLOBAL_OUTPUT_COUNT", [][][2]string{with(global, [2]string{"05", ""}), in, out0, out1}},
+		{"v2 missing PSBT_IN_PREVIOUS_TXID", [][][2]string{global, with(in, [2]string{"0e", ""}), out0, out1}},
+		{"v2 missing PSBT_IN_OUTPUT_INDEX", [][][2]string{global, with(in, [2]string{"0f", ""}), out0, out1}},
+		{"v2 missing PSBT_OUT_AMOUNT", [][][2]string{global, in, with(out0, [2]string{"03", ""}), out1}},
+		{"v2 missing PSBT_OUT_SCRIPT", [][][2]string{global, in, out0, with(out1, [2]string{"04", ""})}},
+		{"v2 missing an input", [][][2]string{global2, in, out0, out1}},
+		{"v2 PSBT_IN_REQUIRED_TIME_LOCKTIME < 500000000", [][][2]string{global, with(in, [2]string{"11", "ff64cd1d"}), out0, out1}},
+		{"v2 PSBT_IN_REQUIRED_HEIGHT_LOCKTIME >= 500000000", [][][2]string{global, with(in, [2]string{"12", "0065cd1d"}), out0, out1}},
+		{"v2 PSBT_IN_REQUIRED_HEIGHT_LOCKTIME 0", [][][2]string{global, with(in, [2]string{"12", "00000000"}), out0, out1}},
+		{"v2 height and time locktimes", [][][2]string{global2, with(in, height), with(in1, time), out0, out1}},
+		{"v2 NonWitnessUTXO of another transaction", [][][2]string{global, with(in1, [2]string{"00", bip370PrevTx}), out0, out1}},
+		{"version 1", [][][2]string{with(global, [2]string{"fb", "01000000"}), in, out0, out1}},
+	} {
+		if _, err := ParsePSBT(buildPSBT(t, c.maps...)); err == nil {
+			t.Errorf("%s: parsed", c.name)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package cckat
+
+import (
+	"encoding/hex"
+	"math/big"
+	"strings"
+)
+
+// PublicKey is a secp256k1 public key which is checked to lie on the curve.
+// It is stored in uncompressed format (0x04 || X || Y), so it can be passed directly
+// to the GetAddress* functions and to any function which accepts a public key as []byte.
+type PublicKey []byte
+
+// ParsePubKey parses the public key b in one of the formats:
+// compressed (33 bytes, 0x02/0x03 prefix), uncompressed (65 bytes, 0x04 prefix),
+// hybrid (65 bytes, 0x06/0x07 prefix) or X-only (32 bytes, BIP340, even Y is implied).
+// Returns nil and InvPubKeyF if the format is invalid, CoordOutOfR or NoSuchPoin if the point is not on the curve.
+func ParsePubKey(b []byte) (PublicKey, error) {
+	switch {
+	case len(b) == 32:
+		x, y, err := PointFromXc(b, true)
+		if err != nil {
+			return nil, err
+		}
+		return NewPublicKey(x, y)
+	case len(b) == 33 && (b[0] == 0x02 || b[0] == 0x03):
+		x, y, err := PointFromXc(b[1:], b[0] == 0x02)
+		if err != nil {
+			return nil, err
+		}
+		return NewPublicKey(x, y)
+	case len(b) == 65 && (b[0] == 0x04 || b[0] == 0x06 || b[0] == 0x07):
+		x := new(big.Int).SetBytes(b[1:33])
+		y := new(big.Int).SetBytes(b[33:])
+		if b[0] != 0x04 && uint(b[0]&1) != y.Bit(0) {
+			return nil, InvPubKeyF
+		}
+		return NewPublicKey(x, y)
+	}
+	return nil, InvPubKeyF
+}
+
+// ParsePubKeyHex parses the hex encoded public key s. See ParsePubKey.
+func ParsePubKeyHex(s string) (PublicKey, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s = s[2:]
+	}
+	if !isHex(s) {
+		return nil, InvHexStr
+	}
+	b, _ := hex.DecodeString(s)
+	return ParsePubKey(b)
+}
+
+// NewPublicKey returns the public key for the point (x, y).
+// Returns nil and CoordOutOfR if a coordinate is not less than P, NoSuchPoin if the point is not on the curve.
+func NewPublicKey(x, y *big.Int) (PublicKey, error) {
+	if x.Sign() < 0 || y.Sign() < 0 || x.Cmp(secp256k1.P) >= 0 || y.Cmp(secp256k1.P) >= 0 {
+		return nil, CoordOutOfR
+	}
+	if !secp256k1.IsOnCurve(x, y) {
+		return nil, NoSuchPoin
+	}
+	p := make(PublicKey, 1, 65)
+	p[0] = 0x04
+	p = append(p, bytesFull(x)...)
+	return append(p, bytesFull(y)...), nil
+}
+
+// IsValid returns true if p has the correct format and lies on the curve.
+func (p PublicKey) IsValid() bool {
+	if len(p) != 65 || p[0] != 0x04 {
+		return false
+	}
+	_, err := NewPublicKey(p.Point())
+	return err == nil
+}
+
+// Point returns the x and y coordinates of p.
+func (p PublicKey) Point() (x, y *big.Int) {
+	return new(big.Int).SetBytes(p[1:33]), new(big.Int).SetBytes(p[33:65])
+}
+
+// HasEvenY returns true if the Y coordinate of p is even.
+func (p PublicKey) HasEvenY() bool {
+	return p[64]&1 == 0
+}
+
+// Uncompressed returns p in uncompressed format (65 bytes, 0x04 || X || Y).
+func (p PublicKey) Uncompressed() []byte {
+	return append([]byte(nil), p[:65]...)
+}
+
+// Compressed returns p in compressed format (33 bytes, 0x02/0x03 || X).
+func (p PublicKey) Compressed() []byte {
+	res := make([]byte, 1, 33)
+	res[0] = 0x02 | p[64]&1
+	return append(res, p[1:33]...)
+}
+
+// Hybrid returns p in hybrid format (65 bytes, 0x06/0x07 || X || Y).
+func (p PublicKey) Hybrid() []byte {
+	res := p.Uncompressed()
+	res[0] = 0x06 | p[64]&1
+	return res
+}
+
+// XOnly returns the X-only (BIP340) public key (32 bytes).
+func (p PublicKey) XOnly() []byte {
+	return append([]byte(nil), p[1:33]...)
+}
+
+// Hex returns p in compressed format if comp == true or in uncompressed format if comp == false as hex string.
+func (p PublicKey) Hex(comp bool) string {
+	if comp {
+		return hex.EncodeToString(p.Compressed())
+	}
+	return hex.EncodeToString(p.Uncompressed())
+}
+
+// Address returns the address of the type t for p (Bitcoin mainnet). Use GetAddress for other networks.
+func (p PublicKey) Address(t AddressType) (string, error) {
+	return GetAddress(p, t, BTCMain)
+}
@@ -0,0 +1,94 @@
+package cckat
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+const (
+	gX = "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"
+	gY = "483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8"
+	// the X coordinate of no point on the curve (BIP340 test vector 5)
+	xNotOnCurve = "eefdea4cdb677750a420fee807eacf21eb9898ae79b9768766e4faa04a2d4a34"
+	// the public key 3G with the odd Y coordinate
+	g3Comp = "03f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9"
+)
+
+func TestParsePubKey(t *testing.T) {
+	uncomp := mustHex(t, "04"+gX+gY)
+	for _, s := range []string{gX, "02" + gX, "04" + gX + gY, "06" + gX + gY} {
+		p, err := ParsePubKey(mustHex(t, s))
+		if err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+		if !bytes.Equal(p, uncomp) || !p.IsValid() {
+			t.Errorf("%s: got %x", s, []byte(p))
+		}
+	}
+	p, _ := ParsePubKey(uncomp)
+	if got := hex.EncodeToString(p.Compressed()); got != "02"+gX {
+		t.Errorf("Compressed: %s", got)
+	}
+	if got := hex.EncodeToString(p.Hybrid()); got != "06"+gX+gY {
+		t.Errorf("Hybrid: %s", got)
+	}
+	if got := hex.EncodeToString(p.XOnly()); got != gX {
+		t.Errorf("XOnly: %s", got)
+	}
+	if !p.HasEvenY() {
+		t.Error("HasEvenY: false for G")
+	}
+
+	p, err := ParsePubKey(mustHex(t, g3Comp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.HasEvenY() || hex.EncodeToString(p.Compressed()) != g3Comp {
+		t.Errorf("odd Y: got %x", []byte(p))
+	}
+	if p.Hybrid()[0] != 0x07 {
+		t.Errorf("odd Y hybrid prefix %x", p.Hybrid()[0])
+	}
+	if _, err := ParsePubKey(p.Hybrid()); err != nil {
+		t.Errorf("odd Y hybrid: %v", err)
+	}
+}
+
+func TestParsePubKeyInvalid(t *testing.T) {
+	gYOdd := "b7c52588d95c3b9aa25b0403f1eef75702e84bb7597aabe663b82f6f04ef2777" // P - gY
+	for _, c := range []struct {
+		name, key string
+		err       error
+	}{
+		{"empty", "", InvPubKeyF},
+		{"compressed prefix 05", "05" + gX, InvPubKeyF},
+		{"compressed prefix 04", "04" + gX, InvPubKeyF},
+		{"uncompressed prefix 02", "02" + gX + gY, InvPubKeyF},
+		{"uncompressed prefix 05", "05" + gX + gY, InvPubKeyF},
+		{"hybrid wrong parity", "07" + gX + gY, InvPubKeyF},
+		{"hybrid wrong parity odd", "06" + gX + gYOdd, InvPubKeyF},
+		{"truncated", "02" + gX[:60], InvPubKeyF},
+		{"x-only not on curve", xNotOnCurve, NoSuchPoin},
+		{"compressed not on curve", "03" + xNotOnCurve, NoSuchPoin},
+		{"uncompressed not on curve", "04" + gX + gY[:62] + "b9", NoSuchPoin},
+		{"x out of range", "02fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc30", CoordOutOfR},
+		{"y out of range", "04" + gX + "fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc30", CoordOutOfR},
+	} {
+		p, err := ParsePubKey(mustHex(t, c.key))
+		if err != c.err || p != nil {
+			t.Errorf("%s: got %x, %v, want %v", c.name, []byte(p), err, c.err)
+		}
+	}
+}
+
+func TestParsePubKeyHex(t *testing.T) {
+	for _, s := range []string{"02" + gX, "0x02" + gX, " 0X02" + gX + "\n"} {
+		if _, err := ParsePubKeyHex(s); err != nil {
+			t.Errorf("%q: %v", s, err)
+		}
+	}
+	if _, err := ParsePubKeyHex("02" + gX[:63] + "g"); err != InvHexStr {
+		t.Errorf("not hex: %v", err)
+	}
+}
@@ -3,7 +3,6 @@ package cckat
 import (
 	cr "crypto/rand"
 	"io"
-	"log"
 	"math/big"
 )
 
@@ -32,18 +31,31 @@ func RandFieldElementEx(rand io.Reader, ex []byte) (k *big.Int, err error) {
 		k, err = RandFieldElement(rand)
 		return
 	}
-	b, err := getRand(rand)
+	b, err := randBytesEx(rand, ex, secp256k1.BitSize/8+8)
 	if err != nil {
 		return
 	}
-	be, err := exRand(rand, ex, len(b))
-	if err != nil {
+	k = fieldElement(b)
+	return
+}
+
+// randBytesEx returns n random bytes obtained from rand and mixed with the bytes obtained from ex (if len(ex) > 0).
+// See RandFieldElementEx.
+func randBytesEx(rand io.Reader, ex []byte, n int) (b []byte, err error) {
+	b = make([]byte, n)
+	if _, err = io.ReadFull(rand, b); err != nil {
+		return nil, err
+	}
+	if len(ex) == 0 {
 		return
 	}
+	be, err := exRand(rand, ex, n)
+	if err != nil {
+		return nil, err
+	}
 	for i := range b {
 		b[i] ^= be[i]
 	}
-	k = fieldElement(b)
 	return
 }
 
@@ -76,9 +88,10 @@ func exRand(rand io.Reader, b []byte, s int) (e []byte, err error) {
 	for i, n := range nb {
 		ni := int(n) + 1
 		for ii := 0; ii < ni; ii++ {
-			pb, err := cr.Int(rand, l)
+			var pb *big.Int
+			pb, err = cr.Int(rand, l)
 			if err != nil {
-				log.Fatal(err)
+				return nil, err
 			}
 			e[i] ^= b[pb.Int64()]
 		}
@@ -0,0 +1,147 @@
+package cckat
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/big"
+)
+
+var InvRLP = errors.New("invalid RLP encoding")
+
+// RLPEncode returns the RLP encoding of v (Ethereum Recursive Length Prefix).
+// v may be []byte, string, uint64, *big.Int (non-negative, nil is 0) or []any of these types.
+// Integers are encoded as the minimal big-endian byte strings (0 is the empty string).
+func RLPEncode(v any) ([]byte, error) {
+	return rlpAppend(nil, v)
+}
+
+func rlpAppend(buf []byte, v any) ([]byte, error) {
+	switch x := v.(type) {
+	case []byte:
+		if len(x) == 1 && x[0] < 0x80 {
+			return append(buf, x[0]), nil
+		}
+		return append(rlpHeader(buf, 0x80, len(x)), x...), nil
+	case string:
+		return rlpAppend(buf, []byte(x))
+	case uint64:
+		return rlpAppend(buf, new(big.Int).SetUint64(x).Bytes())
+	case *big.Int:
+		if x == nil {
+			return rlpAppend(buf, []byte{})
+		}
+		if x.Sign() < 0 {
+			return nil, InvRLP
+		}
+		return rlpAppend(buf, x.Bytes())
+	case []any:
+		var p []byte
+		for _, it := range x {
+			var err error
+			if p, err = rlpAppend(p, it); err != nil {
+				return nil, err
+			}
+		}
+		return append(rlpHeader(buf, 0xc0, len(p)), p...), nil
+	}
+	return nil, InvRLP
+}
+
+// rlpHeader appends the header of the string (base 0x80) or list (base 0xc0) of length l.
+func rlpHeader(buf []byte, base byte, l int) []byte {
+	if l < 56 {
+		return append(buf, base+byte(l))
+	}
+	lb := new(big.Int).SetUint64(uint64(l)).Bytes()
+	buf = append(buf, base+55+byte(len(lb)))
+	return append(buf, lb...)
+}
+
+// RLPDecode decodes the RLP encoded item b. The strings are returned as []byte, the lists as []any.
+// Returns InvRLP if the encoding is not canonical or b has extra bytes.
+func RLPDecode(b []byte) (any, error) {
+	v, rest, err := rlpDecode(b)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, InvRLP
+	}
+	return v, nil
+}
+
+// rlpDecode decodes the first item of b and returns it and the rest of b.
+func rlpDecode(b []byte) (any, []byte, error) {
+	if len(b) == 0 {
+		return nil, nil, InvRLP
+	}
+	p := b[0]
+	if p < 0x80 {
+		return b[:1], b[1:], nil
+	}
+	list := p >= 0xc0
+	base := byte(0x80)
+	if list {
+		base = 0xc0
+	}
+	var l, h int
+	if p-base < 56 {
+		l, h = int(p-base), 1
+	} else {
+		ll := int(p - base - 55)
+		if len(b) < 1+ll || b[1] == 0 {
+			return nil, nil, InvRLP
+		}
+		var lb [8]byte
+		copy(lb[8-ll:], b[1:1+ll])
+		n := binary.BigEndian.Uint64(lb[:])
+		if n < 56 || n > uint64(len(b)) {
+			return nil, nil, InvRLP
+		}
+		l, h = int(n), 1+ll
+	}
+	if len(b) < h+l {
+		return nil, nil, InvRLP
+	}
+	payload, rest := b[h:h+l], b[h+l:]
+	if !list {
+		if l == 1 && payload[0] < 0x80 {
+			return nil, nil, InvRLP
+		}
+		return payload, rest, nil
+	}
+	items := []any{}
+	for len(payload) > 0 {
+		var it any
+		var err error
+		if it, payload, err = rlpDecode(payload); err != nil {
+			return nil, nil, err
+		}
+		items = append(items, it)
+	}
+	return items, rest, nil
+}
+
+// rlpBytes returns v as a byte string.
+func rlpBytes(v any) ([]byte, bool) {
+	b, ok := v.([]byte)
+	return b, ok
+}
+
+// rlpBig returns the canonical (no leading zeros) integer v.
+func rlpBig(v any) (*big.Int, bool) {
+	b, ok := v.([]byte)
+	if !ok || (len(b) > 0 && b[0] == 0) || len(b) > 32 {
+		return nil, false
+	}
+	return new(big.Int).SetBytes(b), true
+}
+
+// rlpUint returns the canonical 64-bit integer v.
+func rlpUint(v any) (uint64, bool) {
+	x, ok := rlpBig(v)
+	if !ok || !x.IsUint64() {
+		return 0, false
+	}
+	return x.Uint64(), true
+}
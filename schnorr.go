@@ -0,0 +1,192 @@
+package cckat
+
+import (
+	"bytes"
+	cr "crypto/rand"
+	"errors"
+	"io"
+	"math/big"
+)
+
+var (
+	InvAuxRand = errors.New("aux randomness must be 32 bytes long")
+	InvTweak   = errors.New("tweak out of range")
+	SignFailed = errors.New("signing failed")
+)
+
+// SignSchnorr returns the BIP340 Schnorr signature (64 bytes) of msg.
+// aux is 32 bytes of auxiliary randomness. If aux == nil, it is read from crypto/rand.
+// See https://github.com/bitcoin/bips/blob/master/bip-0340.mediawiki
+func (k *PrKey) SignSchnorr(msg, aux []byte) ([]byte, error) {
+	if err := k.isSet(); err != nil {
+		return nil, err
+	}
+	return signSchnorr(&k.k, msg, aux)
+}
+
+func signSchnorr(dp *big.Int, msg, aux []byte) ([]byte, error) {
+	if aux == nil {
+		aux = make([]byte, 32)
+		if _, err := io.ReadFull(cr.Reader, aux); err != nil {
+			return nil, err
+		}
+	}
+	if len(aux) != 32 {
+		return nil, InvAuxRand
+	}
+	px, py := secp256k1.ScalarBaseMult(dp.Bytes())
+	d := new(big.Int).Set(dp)
+	if py.Bit(0) == 1 {
+		d.Sub(secp256k1.N, d)
+		py.Sub(secp256k1.P, py)
+	}
+	pb := bytesFull(px)
+	t := bytesFull(d)
+	ah := taggedHash("BIP0340/aux", aux)
+	for i := range t {
+		t[i] ^= ah[i]
+	}
+	kk := new(big.Int).SetBytes(taggedHash("BIP0340/nonce", concat(t, pb, msg)))
+	kk.Mod(kk, secp256k1.N)
+	if kk.Sign() == 0 {
+		return nil, SignFailed
+	}
+	rx, ry := secp256k1.ScalarBaseMult(kk.Bytes())
+	if ry.Bit(0) == 1 {
+		kk.Sub(secp256k1.N, kk)
+	}
+	rb := bytesFull(rx)
+	e := schnorrChallenge(rb, pb, msg)
+	es, ds, ks := fn.fromBig(e), fn.fromBig(d), fn.fromBig(kk)
+	ss := fn.mul(&es, &ds)
+	ss = fn.add(&ss, &ks)
+	s := fn.toBytes(&ss)
+	sig := append(rb, s[:]...)
+	if !verifySchnorr(px, py, pb, msg, sig) {
+		return nil, SignFailed
+	}
+	return sig, nil
+}
+
+// VerifySchnorr returns true if sig is a valid BIP340 signature of msg for pubKey.
+// pubKey is the X-only public key (32 bytes) or any other format accepted by ParsePubKey,
+// in which case only the X coordinate is used.
+func VerifySchnorr(pubKey, msg, sig []byte) bool {
+	p, err := ParsePubKey(pubKey)
+	if err != nil {
+		return false
+	}
+	return p.VerifySchnorr(msg, sig)
+}
+
+// VerifySchnorr returns true if sig is a valid BIP340 signature of msg for the X-only public key of p.
+func (p PublicKey) VerifySchnorr(msg, sig []byte) bool {
+	if !p.IsValid() {
+		return false
+	}
+	px, py := p.Point()
+	if py.Bit(0) == 1 {
+		py.Sub(secp256k1.P, py)
+	}
+	return verifySchnorr(px, py, p.XOnly(), msg, sig)
+}
+
+// verifySchnorr verifies sig for the point (px, py) with even Y. pb is the X-only encoding of the point.
+func verifySchnorr(px, py *big.Int, pb, msg, sig []byte) bool {
+	if len(sig) != 64 {
+		return false
+	}
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:])
+	if r.Cmp(secp256k1.P) >= 0 || s.Cmp(secp256k1.N) >= 0 {
+		return false
+	}
+	e := schnorrChallenge(sig[:32], pb, msg)
+	e.Sub(secp256k1.N, e)
+	rx, ry := secp256k1.CombinedMult(px, py, s.Bytes(), e.Bytes())
+	if (rx.Sign() == 0 && ry.Sign() == 0) || ry.Bit(0) == 1 {
+		return false
+	}
+	return rx.Cmp(r) == 0
+}
+
+// schnorrChallenge returns int(hash_BIP0340/challenge(R || P || m)) mod n
+func schnorrChallenge(rb, pb, msg []byte) *big.Int {
+	e := new(big.Int).SetBytes(taggedHash("BIP0340/challenge", concat(rb, pb, msg)))
+	return e.Mod(e, secp256k1.N)
+}
+
+// TapTweak returns the private key tweaked with the TapTweak tagged hash of its X-only public key
+// and merkleRoot (BIP341). The X-only public key of the returned key is the P2TR output key,
+// so it can be used with SignSchnorr to spend from the address returned by GetAddressP2TR (merkleRoot == nil)
+// or by the key path of a script tree with the given merkle root.
+func (k *PrKey) TapTweak(merkleRoot []byte) (*PrKey, error) {
+	if err := k.isSet(); err != nil {
+		return nil, err
+	}
+	d := new(big.Int).Set(&k.k)
+	px, py := secp256k1.ScalarBaseMult(d.Bytes())
+	if py.Bit(0) == 1 {
+		d.Sub(secp256k1.N, d)
+	}
+	t, err := tapTweakHash(px, merkleRoot)
+	if err != nil {
+		return nil, err
+	}
+	d.Add(d, t)
+	d.Mod(d, secp256k1.N)
+	tk := &PrKey{a: k.a, uncomp: k.uncomp}
+	return tk.Set(*d)
+}
+
+// SignTaproot returns the BIP340 signature of msg with the private key tweaked for the P2TR output (key path spending).
+// See TapTweak and SignSchnorr.
+func (k *PrKey) SignTaproot(msg, aux, merkleRoot []byte) ([]byte, error) {
+	tk, err := k.TapTweak(merkleRoot)
+	if err != nil {
+		return nil, err
+	}
+	return tk.SignSchnorr(msg, aux)
+}
+
+// TapOutputKey returns the X-only P2TR output key for the internal key pubKey (any format accepted by ParsePubKey)
+// and merkleRoot (nil for key path only outputs).
+func TapOutputKey(pubKey, merkleRoot []byte) ([]byte, error) {
+	qx, _, err := tapOutputKey(pubKey, merkleRoot)
+	if err != nil {
+		return nil, err
+	}
+	return bytesFull(qx), nil
+}
+
+func tapOutputKey(pubKey, merkleRoot []byte) (qx, qy *big.Int, err error) {
+	p, err := ParsePubKey(pubKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	x, y := p.Point()
+	if y.Bit(0) == 1 {
+		y.Sub(secp256k1.P, y)
+	}
+	t, err := tapTweakHash(x, merkleRoot)
+	if err != nil {
+		return nil, nil, err
+	}
+	tx, ty := secp256k1.ScalarBaseMult(t.Bytes())
+	qx, qy = secp256k1.Add(x, y, tx, ty)
+	return qx, qy, nil
+}
+
+// tapTweakHash returns int(hash_TapTweak(x || merkleRoot)). Returns InvTweak if it is not less than n.
+func tapTweakHash(x *big.Int, merkleRoot []byte) (*big.Int, error) {
+	t := new(big.Int).SetBytes(taggedHash("TapTweak", concat(bytesFull(x), merkleRoot)))
+	if t.Cmp(secp256k1.N) >= 0 {
+		return nil, InvTweak
+	}
+	return t, nil
+}
+
+// concat returns the concatenation of b.
+func concat(b ...[]byte) []byte {
+	return bytes.Join(b, nil)
+}
@@ -0,0 +1,69 @@
+package cckat
+
+import (
+	"encoding/csv"
+	"encoding/hex"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestSchnorrVectors runs the official BIP340 test vectors
+// (https://github.com/bitcoin/bips/blob/master/bip-0340/test-vectors.csv).
+func TestSchnorrVectors(t *testing.T) {
+	f, err := os.Open("testdata/bip340-test-vectors.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range rows[1:] {
+		idx, sk, pub, aux, msg, sig, result, comment := r[0], r[1], r[2], r[3], r[4], r[5], r[6], r[7]
+		want := result == "TRUE"
+		if sk != "" {
+			k := testKey(t, sk)
+			if got := strings.ToUpper(hex.EncodeToString(k.PubK().XOnly())); got != pub {
+				t.Errorf("%s: public key %s, want %s", idx, got, pub)
+			}
+			s, err := k.SignSchnorr(mustHex(t, msg), mustHex(t, aux))
+			if err != nil {
+				t.Fatalf("%s: %v", idx, err)
+			}
+			if got := strings.ToUpper(hex.EncodeToString(s)); got != sig {
+				t.Errorf("%s: signature %s, want %s", idx, got, sig)
+			}
+		}
+		if got := VerifySchnorr(mustHex(t, pub), mustHex(t, msg), mustHex(t, sig)); got != want {
+			t.Errorf("%s: verification %v, want %v (%s)", idx, got, want, comment)
+		}
+	}
+}
+
+func TestTapTweak(t *testing.T) {
+	// BIP86 test vector: m/86'/0'/0'/0/0 of "abandon ... about"
+	k := testKey(t, "41f41d69260df4cf277826a9b65a3717e4eeddbeedf637f212ca096576479361")
+	internal := "cc8a4bc64d897bddc5fbc2f670f7a8ba0b386779106cf1223c6fc5d7cd6fc115"
+	output := "a60869f0dbcf1dc659c9cecbaf8050135ea9e8cdc487053f1dc6880949dc684c"
+	if got := hex.EncodeToString(k.PubK().XOnly()); got != internal {
+		t.Fatalf("internal key %s", got)
+	}
+	q, err := TapOutputKey(mustHex(t, internal), nil)
+	if err != nil || hex.EncodeToString(q) != output {
+		t.Fatalf("TapOutputKey %x, %v", q, err)
+	}
+	tk, err := k.TapTweak(nil)
+	if err != nil || hex.EncodeToString(tk.PubK().XOnly()) != output {
+		t.Fatalf("TapTweak %v", err)
+	}
+	msg := mustHex(t, strings.Repeat("42", 32))
+	sig, err := k.SignTaproot(msg, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !VerifySchnorr(q, msg, sig) || VerifySchnorr(mustHex(t, internal), msg, sig) {
+		t.Error("SignTaproot: the signature is not valid for the output key only")
+	}
+}
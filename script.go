@@ -0,0 +1,149 @@
+package cckat
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+)
+
+var InvScriptLen = errors.New("invalid script length")
+
+// maxScriptElementSize is the maximum size of the pushed data (the redeem and witness scripts of P2SH and P2WSH).
+const maxScriptElementSize = 520
+
+// The array of functions of the output script (scriptPubKey) of address.
+// All functions accept a public key in any format accepted by ParsePubKey.
+var pkScripts = [MaxType]func([]byte) ([]byte, error){
+	P2PKH:       pkScriptP2PKHComp,
+	P2PKHUncomp: pkScriptP2PKHUncomp,
+	P2SH:        pkScriptP2SHP2WPKH,
+	P2WPKH:      pkScriptP2WPKH,
+	P2TR:        pkScriptP2TR,
+	P2SHP2WPKH:  pkScriptP2SHP2WPKH,
+}
+
+// PkScript returns the output script (scriptPubKey) of the address of the type t for pubKey.
+// Returns UnsupAddrType for ETH and the script address types.
+func PkScript(pubKey []byte, t AddressType) ([]byte, error) {
+	if t >= MaxType {
+		return nil, InvAddrType
+	}
+	if pkScripts[t] == nil {
+		return nil, UnsupAddrType
+	}
+	return pkScripts[t](pubKey)
+}
+
+func pkScriptP2PKHComp(pubKey []byte) ([]byte, error) {
+	return pkScriptP2PKH(pubKey, true)
+}
+
+func pkScriptP2PKHUncomp(pubKey []byte) ([]byte, error) {
+	return pkScriptP2PKH(pubKey, false)
+}
+
+func pkScriptP2PKH(pubKey []byte, comp bool) ([]byte, error) {
+	p, err := PubKeyCompUncomp(pubKey, comp)
+	if err != nil {
+		return nil, err
+	}
+	return p2pkhScript(HashPubKey(p)), nil
+}
+
+func pkScriptP2SHP2WPKH(pubKey []byte) ([]byte, error) {
+	s, err := RedeemScriptP2SHP2WPKH(pubKey)
+	if err != nil {
+		return nil, err
+	}
+	return p2shScript(HashPubKey(s)), nil
+}
+
+func pkScriptP2WPKH(pubKey []byte) ([]byte, error) {
+	p, err := PubKeyCompUncomp(pubKey, true)
+	if err != nil {
+		return nil, err
+	}
+	return witnessScript(0, HashPubKey(p)), nil
+}
+
+func pkScriptP2TR(pubKey []byte) ([]byte, error) {
+	q, err := TapOutputKey(pubKey, nil)
+	if err != nil {
+		return nil, err
+	}
+	return witnessScript(1, q), nil
+}
+
+// AddressScript returns the output script (scriptPubKey) of the Bitcoin mainnet address a. See Network.AddressScript.
+func AddressScript(a string) ([]byte, error) {
+	return BTCMain.AddressScript(a)
+}
+
+// AddressScript returns the output script (scriptPubKey) of the address a of the network n.
+// The segwit addresses of all witness versions are accepted.
+func (n *Network) AddressScript(a string) ([]byte, error) {
+	a = strings.TrimSpace(a)
+	if n.Bech32HRP != "" && strings.HasPrefix(strings.ToLower(a), n.Bech32HRP+"1") {
+		hrp, ver, prog, err := Bech32Decode(a)
+		if err != nil {
+			return nil, err
+		}
+		if hrp != n.Bech32HRP {
+			return nil, InvHRP
+		}
+		return witnessScript(byte(ver), prog), nil
+	}
+	t, h, err := n.parseAddressBase58(a)
+	if err != nil {
+		return nil, err
+	}
+	if t == P2SH {
+		return p2shScript(h), nil
+	}
+	return p2pkhScript(h), nil
+}
+
+// p2pkhScript returns the P2PKH script OP_DUP OP_HASH160 <h> OP_EQUALVERIFY OP_CHECKSIG.
+func p2pkhScript(h []byte) []byte {
+	return append(append([]byte{0x76, 0xa9, 0x14}, h...), 0x88, 0xac)
+}
+
+// p2shScript returns the P2SH script OP_HASH160 <h> OP_EQUAL.
+func p2shScript(h []byte) []byte {
+	return append(append([]byte{0xa9, 0x14}, h...), 0x87)
+}
+
+// witnessScript returns the segwit output script OP_ver <prog>.
+func witnessScript(ver byte, prog []byte) []byte {
+	op := ver
+	if ver > 0 {
+		op = 0x50 + ver
+	}
+	return append([]byte{op, byte(len(prog))}, prog...)
+}
+
+// appendSmallInt appends the minimal push of the integer 0 <= n <= 127 to b (OP_0, OP_1 - OP_16 or 1 byte push).
+func appendSmallInt(b []byte, n int) []byte {
+	switch {
+	case n == 0:
+		return append(b, 0x00)
+	case n <= 16:
+		return append(b, 0x50+byte(n))
+	}
+	return append(b, 0x01, byte(n))
+}
+
+// appendPush appends the script operation pushing data to b (minimal push for data longer than 1 byte).
+func appendPush(b, data []byte) []byte {
+	switch l := len(data); {
+	case l < 0x4c:
+		b = append(b, byte(l))
+	case l <= 0xff:
+		b = append(b, 0x4c, byte(l))
+	case l <= 0xffff:
+		b = binary.LittleEndian.AppendUint16(append(b, 0x4d), uint16(l))
+	default:
+		b = binary.LittleEndian.AppendUint32(append(b, 0x4e), uint32(l))
+	}
+	return append(b, data...)
+}
@@ -188,24 +188,33 @@ func (curve *Secp256k1C) addJacobian(x1, y1, z1, x2, y2, z2 *big.Int) (*big.Int,
 	return x3, y3, z3
 }
 
+// ScalarMult returns k*(Bx,By), k is a big-endian integer (it is reduced modulo N).
+// The multiplication is constant time in k: fixed-width field and scalar arithmetic (see point.go),
+// GLV endomorphism with fixed 4-bit windows (see glv.go) and complete addition formulas.
 func (curve *Secp256k1C) ScalarMult(Bx, By *big.Int, k []byte) (*big.Int, *big.Int) {
-	Bz := new(big.Int).SetInt64(1)
-	x, y, z := new(big.Int), new(big.Int), new(big.Int)
-
-	for _, b := range k {
-		for bitNum := 0; bitNum < 8; bitNum++ {
-			x, y, z = curve.doubleJacobian(x, y, z)
-			if b&0x80 == 0x80 {
-				x, y, z = curve.addJacobian(Bx, By, Bz, x, y, z)
-			}
-			b <<= 1
-		}
+	if Bx.Sign() == 0 && By.Sign() == 0 {
+		return new(big.Int), new(big.Int)
 	}
-	return curve.affineFromJacobian(x, y, z)
+	s := scalarBytes(k)
+	var p point
+	return p.scalarMult(newPoint(Bx, By), &s).affine()
 }
 
+// ScalarBaseMult returns k*G, k is a big-endian integer (it is reduced modulo N).
+// It uses the table of the multiples of G precomputed on the first call and is constant time in k.
 func (curve *Secp256k1C) ScalarBaseMult(k []byte) (*big.Int, *big.Int) {
-	return curve.ScalarMult(curve.Gx, curve.Gy, k)
+	s := scalarBytes(k)
+	var p point
+	return p.scalarBaseMult(&s).affine()
+}
+
+// CombinedMult returns baseScalar*G + scalar*(Bx,By) (big-endian integers reduced modulo N).
+// It uses the GLV endomorphism and the interleaved wNAF method and is NOT constant time,
+// so it must be used only with public scalars, e.g. for signature verification.
+func (curve *Secp256k1C) CombinedMult(Bx, By *big.Int, baseScalar, scalar []byte) (*big.Int, *big.Int) {
+	ka, kb := scalarBytes(baseScalar), scalarBytes(scalar)
+	var p point
+	return p.combinedMult(newPoint(Bx, By), &ka, &kb).affine()
 }
 
 func (curve *Secp256k1C) Params() *Secp256k1C {
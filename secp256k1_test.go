@@ -0,0 +1,153 @@
+package cckat
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// jacobianScalarMult is the former big.Int double-and-add implementation of ScalarMult, the reference
+// for the fixed-width arithmetic.
+func jacobianScalarMult(Bx, By *big.Int, k []byte) (*big.Int, *big.Int) {
+	Bz := new(big.Int).SetInt64(1)
+	x, y, z := new(big.Int), new(big.Int), new(big.Int)
+	for _, b := range k {
+		for bitNum := 0; bitNum < 8; bitNum++ {
+			x, y, z = secp256k1.doubleJacobian(x, y, z)
+			if b&0x80 == 0x80 {
+				x, y, z = secp256k1.addJacobian(Bx, By, Bz, x, y, z)
+			}
+			b <<= 1
+		}
+	}
+	return secp256k1.affineFromJacobian(x, y, z)
+}
+
+// testScalars returns the edge scalars (0, 1, N-1, N/2, 2^128±1, N) and n random scalars.
+func testScalars(n int) [][]byte {
+	one := big.NewInt(1)
+	p128 := new(big.Int).Lsh(one, 128)
+	ks := []*big.Int{
+		new(big.Int),
+		one,
+		new(big.Int).Sub(secp256k1.N, one),
+		new(big.Int).Rsh(secp256k1.N, 1),
+		new(big.Int).Add(new(big.Int).Rsh(secp256k1.N, 1), one),
+		new(big.Int).Sub(p128, one),
+		new(big.Int).Add(p128, one),
+		secp256k1.N,
+	}
+	r := rand.New(rand.NewSource(1))
+	for range n {
+		ks = append(ks, new(big.Int).Rand(r, secp256k1.N))
+	}
+	res := make([][]byte, len(ks))
+	for i, k := range ks {
+		res[i] = bytesFull(k)
+	}
+	return res
+}
+
+func TestScalarBaseMult(t *testing.T) {
+	for _, k := range testScalars(64) {
+		x, y := secp256k1.ScalarBaseMult(k)
+		rx, ry := jacobianScalarMult(secp256k1.Gx, secp256k1.Gy, k)
+		if x.Cmp(rx) != 0 || y.Cmp(ry) != 0 {
+			t.Errorf("%x: got (%x, %x), want (%x, %x)", k, x, y, rx, ry)
+		}
+	}
+}
+
+func TestScalarMult(t *testing.T) {
+	px, py := jacobianScalarMult(secp256k1.Gx, secp256k1.Gy, []byte("a point for the ScalarMult test"))
+	ks := testScalars(64)
+	for _, b := range [][2]*big.Int{{secp256k1.Gx, secp256k1.Gy}, {px, py}} {
+		for i, k := range ks {
+			x, y := secp256k1.ScalarMult(b[0], b[1], k)
+			rx, ry := jacobianScalarMult(b[0], b[1], k)
+			if x.Cmp(rx) != 0 || y.Cmp(ry) != 0 {
+				t.Errorf("%x: got (%x, %x), want (%x, %x)", k, x, y, rx, ry)
+			}
+			kb := ks[(i+1)%len(ks)]
+			x, y = secp256k1.CombinedMult(b[0], b[1], k, kb)
+			gx, gy := jacobianScalarMult(secp256k1.Gx, secp256k1.Gy, k)
+			rx, ry = jacobianScalarMult(b[0], b[1], kb)
+			rx, ry = secp256k1.Add(gx, gy, rx, ry)
+			if x.Cmp(rx) != 0 || y.Cmp(ry) != 0 {
+				t.Errorf("CombinedMult %x, %x: got (%x, %x), want (%x, %x)", k, kb, x, y, rx, ry)
+			}
+		}
+	}
+}
+
+func TestPubKeys(t *testing.T) {
+	var ks []*big.Int
+	for i, k := range testScalars(64) {
+		if i != 0 && i != 7 { // not 0 or N
+			ks = append(ks, new(big.Int).SetBytes(k))
+		}
+	}
+	for _, uncomp := range []bool{false, true} {
+		for i, p := range PubKeys(ks, uncomp) {
+			if string(p) != string(PubKey(ks[i], uncomp)) {
+				t.Errorf("%x: got %x, want %x", ks[i], p, PubKey(ks[i], uncomp))
+			}
+		}
+	}
+}
+
+func BenchmarkScalarBaseMult(b *testing.B) {
+	k := testScalars(1)[8]
+	secp256k1.ScalarBaseMult(k) // build the table
+	b.Run("jacobian", func(b *testing.B) {
+		for b.Loop() {
+			jacobianScalarMult(secp256k1.Gx, secp256k1.Gy, k)
+		}
+	})
+	b.Run("table", func(b *testing.B) {
+		for b.Loop() {
+			secp256k1.ScalarBaseMult(k)
+		}
+	})
+}
+
+func BenchmarkScalarMult(b *testing.B) {
+	k := testScalars(1)[8]
+	b.Run("jacobian", func(b *testing.B) {
+		for b.Loop() {
+			jacobianScalarMult(secp256k1.Gx, secp256k1.Gy, k)
+		}
+	})
+	b.Run("glv", func(b *testing.B) {
+		for b.Loop() {
+			secp256k1.ScalarMult(secp256k1.Gx, secp256k1.Gy, k)
+		}
+	})
+}
+
+func BenchmarkPubKey(b *testing.B) {
+	k := new(big.Int).SetBytes(testScalars(1)[8])
+	PubKey(k, false)
+	b.Run("jacobian", func(b *testing.B) {
+		for b.Loop() {
+			x, y := jacobianScalarMult(secp256k1.Gx, secp256k1.Gy, k.Bytes())
+			p, _ := NewPublicKey(x, y)
+			p.Compressed()
+		}
+	})
+	b.Run("table", func(b *testing.B) {
+		for b.Loop() {
+			PubKey(k, false)
+		}
+	})
+	ks := make([]*big.Int, 1024)
+	for i, s := range testScalars(len(ks))[8:] {
+		ks[i] = new(big.Int).SetBytes(s)
+	}
+	b.Run("batch", func(b *testing.B) {
+		for b.Loop() {
+			PubKeys(ks, false)
+		}
+		b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*len(ks)), "ns/key")
+	})
+}
@@ -0,0 +1,185 @@
+package cckat
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+)
+
+// SigHashType is the signature hash type of the transaction signature.
+type SigHashType uint32
+
+// The signature hash types
+const (
+	SigHashDefault      SigHashType = 0x00 // taproot only, same as SigHashAll
+	SigHashAll          SigHashType = 0x01
+	SigHashNone         SigHashType = 0x02
+	SigHashSingle       SigHashType = 0x03
+	SigHashAnyOneCanPay SigHashType = 0x80
+)
+
+var (
+	InvSigHashType = errors.New("invalid signature hash type")
+	InvTxIndex     = errors.New("input index out of range")
+	InvPrevOuts    = errors.New("invalid previous outputs")
+)
+
+func (t SigHashType) base() SigHashType {
+	return t & 0x1f
+}
+
+func (t SigHashType) anyOneCanPay() bool {
+	return t&SigHashAnyOneCanPay != 0
+}
+
+func sha256Sum(b []byte) []byte {
+	h := sha256.Sum256(b)
+	return h[:]
+}
+
+// SigHashWitnessV0 returns the hash of the input idx of tx to be signed for the segwit v0 output (BIP143).
+// scriptCode is the P2PKH script of the pubkey hash for P2WPKH or the witness script for P2WSH,
+// amount is the value of the spent output.
+func (tx *Tx) SigHashWitnessV0(idx int, scriptCode []byte, amount int64, ht SigHashType) ([]byte, error) {
+	if idx < 0 || idx >= len(tx.TxIn) {
+		return nil, InvTxIndex
+	}
+	base := ht.base()
+	var hashPrevouts, hashSequence, hashOutputs [32]byte
+	if !ht.anyOneCanPay() {
+		var b, s []byte
+		for _, in := range tx.TxIn {
+			b = in.PrevOut.append(b)
+			s = binary.LittleEndian.AppendUint32(s, in.Sequence)
+		}
+		hashPrevouts = [32]byte(dsha256(b))
+		if base != SigHashSingle && base != SigHashNone {
+			hashSequence = [32]byte(dsha256(s))
+		}
+	}
+	switch {
+	case base != SigHashSingle && base != SigHashNone:
+		var b []byte
+		for _, out := range tx.TxOut {
+			b = out.append(b)
+		}
+		hashOutputs = [32]byte(dsha256(b))
+	case base == SigHashSingle && idx < len(tx.TxOut):
+		hashOutputs = [32]byte(dsha256(tx.TxOut[idx].append(nil)))
+	}
+	in := tx.TxIn[idx]
+	b := binary.LittleEndian.AppendUint32(nil, uint32(tx.Version))
+	b = append(b, hashPrevouts[:]...)
+	b = append(b, hashSequence[:]...)
+	b = in.PrevOut.append(b)
+	b = appendVarBytes(b, scriptCode)
+	b = binary.LittleEndian.AppendUint64(b, uint64(amount))
+	b = binary.LittleEndian.AppendUint32(b, in.Sequence)
+	b = append(b, hashOutputs[:]...)
+	b = binary.LittleEndian.AppendUint32(b, tx.LockTime)
+	b = binary.LittleEndian.AppendUint32(b, uint32(ht))
+	return dsha256(b), nil
+}
+
+// SigHashTaproot returns the hash of the input idx of tx to be signed for the taproot output (BIP341).
+// prevOuts are the outputs spent by all the inputs of tx. leafHash is nil for the key path spending
+// or the tapleaf hash of the executed script for the script path spending (BIP342). The annex is not supported.
+func (tx *Tx) SigHashTaproot(idx int, prevOuts []*TxOut, ht SigHashType, leafHash []byte) ([]byte, error) {
+	if idx < 0 || idx >= len(tx.TxIn) {
+		return nil, InvTxIndex
+	}
+	if len(prevOuts) != len(tx.TxIn) {
+		return nil, InvPrevOuts
+	}
+	base := ht.base()
+	if ht > 0xff || ht&0x7c != 0 || (ht != SigHashDefault && base == SigHashDefault) {
+		return nil, InvSigHashType
+	}
+	if base == SigHashSingle && idx >= len(tx.TxOut) {
+		return nil, InvTxIndex
+	}
+	b := []byte{0x00, byte(ht)}
+	b = binary.LittleEndian.AppendUint32(b, uint32(tx.Version))
+	b = binary.LittleEndian.AppendUint32(b, tx.LockTime)
+	if !ht.anyOneCanPay() {
+		var po, am, sp, sq []byte
+		for i, in := range tx.TxIn {
+			po = in.PrevOut.append(po)
+			am = binary.LittleEndian.AppendUint64(am, uint64(prevOuts[i].Value))
+			sp = appendVarBytes(sp, prevOuts[i].PkScript)
+			sq = binary.LittleEndian.AppendUint32(sq, in.Sequence)
+		}
+		b = append(b, sha256Sum(po)...)
+		b = append(b, sha256Sum(am)...)
+		b = append(b, sha256Sum(sp)...)
+		b = append(b, sha256Sum(sq)...)
+	}
+	if base != SigHashSingle && base != SigHashNone {
+		var o []byte
+		for _, out := range tx.TxOut {
+			o = out.append(o)
+		}
+		b = append(b, sha256Sum(o)...)
+	}
+	var spendType byte
+	if leafHash != nil {
+		spendType = 2
+	}
+	b = append(b, spendType)
+	in := tx.TxIn[idx]
+	if ht.anyOneCanPay() {
+		b = in.PrevOut.append(b)
+		b = prevOuts[idx].append(b)
+		b = binary.LittleEndian.AppendUint32(b, in.Sequence)
+	} else {
+		b = binary.LittleEndian.AppendUint32(b, uint32(idx))
+	}
+	if base == SigHashSingle {
+		b = append(b, sha256Sum(tx.TxOut[idx].append(nil))...)
+	}
+	if leafHash != nil {
+		b = append(b, leafHash...)
+		b = append(b, 0x00, 0xff, 0xff, 0xff, 0xff)
+	}
+	return taggedHash("TapSighash", b), nil
+}
+
+// SigHashLegacy returns the hash of the input idx of tx to be signed for the legacy (pre-segwit) output.
+// subScript is the script of the spent output (or the redeem script for P2SH), OP_CODESEPARATOR is not handled.
+// The hash 1 is returned for SigHashSingle without the corresponding output as Bitcoin does.
+func (tx *Tx) SigHashLegacy(idx int, subScript []byte, ht SigHashType) ([]byte, error) {
+	if idx < 0 || idx >= len(tx.TxIn) {
+		return nil, InvTxIndex
+	}
+	base := ht.base()
+	if base == SigHashSingle && idx >= len(tx.TxOut) {
+		h := make([]byte, 32)
+		h[0] = 1
+		return h, nil
+	}
+	c := &Tx{Version: tx.Version, LockTime: tx.LockTime}
+	for i, in := range tx.TxIn {
+		if ht.anyOneCanPay() && i != idx {
+			continue
+		}
+		ci := &TxIn{PrevOut: in.PrevOut, Sequence: in.Sequence}
+		if i == idx {
+			ci.ScriptSig = subScript
+		} else if base == SigHashNone || base == SigHashSingle {
+			ci.Sequence = 0
+		}
+		c.TxIn = append(c.TxIn, ci)
+	}
+	switch base {
+	case SigHashNone:
+	case SigHashSingle:
+		for range idx {
+			c.TxOut = append(c.TxOut, &TxOut{Value: -1})
+		}
+		c.TxOut = append(c.TxOut, tx.TxOut[idx])
+	default:
+		c.TxOut = tx.TxOut
+	}
+	b := binary.LittleEndian.AppendUint32(c.SerializeNoWitness(), uint32(ht))
+	return dsha256(b), nil
+}
@@ -0,0 +1,182 @@
+package cckat
+
+import (
+	"bytes"
+	"errors"
+)
+
+// TapScriptLeafVersion is the leaf version of the BIP342 tapscript.
+const TapScriptLeafVersion byte = 0xc0
+
+// maxTapTreeDepth is the maximum depth of the leaves of a taproot script tree (BIP341).
+const maxTapTreeDepth = 128
+
+var InvTapTree = errors.New("invalid taproot script tree")
+
+// TapLeaf is a script of the taproot script tree with its leaf version.
+type TapLeaf struct {
+	Version byte // even, not 0x50; TapScriptLeafVersion for tapscript
+	Script  []byte
+}
+
+// NewTapLeaf returns the tapscript leaf of script.
+func NewTapLeaf(script []byte) TapLeaf {
+	return TapLeaf{Version: TapScriptLeafVersion, Script: script}
+}
+
+// Hash returns the TapLeaf tagged hash of the leaf version and the script (the tapleaf hash).
+func (l TapLeaf) Hash() []byte {
+	return taggedHash("TapLeaf", appendVarBytes([]byte{l.Version}, l.Script))
+}
+
+func (l TapLeaf) valid() bool {
+	return l.Version&1 == 0 && l.Version != 0x50
+}
+
+// TapBranchHash returns the TapBranch tagged hash of the child hashes a and b (sorted lexicographically).
+func TapBranchHash(a, b []byte) []byte {
+	if bytes.Compare(a, b) > 0 {
+		a, b = b, a
+	}
+	return taggedHash("TapBranch", concat(a, b))
+}
+
+// TapTree is a taproot script tree (BIP341): the leaf if Leaf is set or the branch of Left and Right.
+type TapTree struct {
+	Leaf        *TapLeaf
+	Left, Right *TapTree
+}
+
+// NewTapTree returns the balanced script tree of leaves: the first half of leaves (rounded up) is in the left
+// subtree and the rest is in the right one. Use the TapTree fields to build the trees of other shapes.
+func NewTapTree(leaves ...TapLeaf) (*TapTree, error) {
+	switch len(leaves) {
+	case 0:
+		return nil, InvTapTree
+	case 1:
+		if !leaves[0].valid() {
+			return nil, InvTapTree
+		}
+		return &TapTree{Leaf: &leaves[0]}, nil
+	}
+	h := (len(leaves) + 1) / 2
+	l, err := NewTapTree(leaves[:h]...)
+	if err != nil {
+		return nil, err
+	}
+	r, err := NewTapTree(leaves[h:]...)
+	if err != nil {
+		return nil, err
+	}
+	return &TapTree{Left: l, Right: r}, nil
+}
+
+// tapLeafPath is a leaf with its merkle path: the hashes of the siblings from the leaf to the root.
+type tapLeafPath struct {
+	leaf TapLeaf
+	path []byte
+}
+
+// hashPaths returns the hash of t and its leaves in the depth-first order with their merkle paths.
+func (t *TapTree) hashPaths() ([]byte, []tapLeafPath, error) {
+	switch {
+	case t == nil:
+		return nil, nil, InvTapTree
+	case t.Leaf != nil:
+		if t.Left != nil || t.Right != nil || !t.Leaf.valid() {
+			return nil, nil, InvTapTree
+		}
+		return t.Leaf.Hash(), []tapLeafPath{{leaf: *t.Leaf}}, nil
+	}
+	lh, lp, err := t.Left.hashPaths()
+	if err != nil {
+		return nil, nil, err
+	}
+	rh, rp, err := t.Right.hashPaths()
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range lp {
+		lp[i].path = append(lp[i].path, rh...)
+	}
+	for i := range rp {
+		rp[i].path = append(rp[i].path, lh...)
+	}
+	res := append(lp, rp...)
+	for _, p := range res {
+		if len(p.path) > 32*maxTapTreeDepth {
+			return nil, nil, InvTapTree
+		}
+	}
+	return TapBranchHash(lh, rh), res, nil
+}
+
+// MerkleRoot returns the merkle root of t (the hash used to tweak the internal key).
+func (t *TapTree) MerkleRoot() ([]byte, error) {
+	h, _, err := t.hashPaths()
+	return h, err
+}
+
+// Leaves returns the leaves of t in the depth-first order (the order of the leaf indexes of ControlBlock).
+func (t *TapTree) Leaves() ([]TapLeaf, error) {
+	_, ps, err := t.hashPaths()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]TapLeaf, len(ps))
+	for i, p := range ps {
+		res[i] = p.leaf
+	}
+	return res, nil
+}
+
+// ControlBlock returns the control block for spending the leaf i (see Leaves) of the P2TR output with
+// the internal key pubKey (any format accepted by ParsePubKey) and the script tree t:
+// the leaf version | the Y parity of the output key, the X-only internal key and the merkle path.
+// The witness of the script path spending is the script inputs, the script and the control block.
+func (t *TapTree) ControlBlock(pubKey []byte, i int) ([]byte, error) {
+	root, ps, err := t.hashPaths()
+	if err != nil {
+		return nil, err
+	}
+	if i < 0 || i >= len(ps) {
+		return nil, InvTapTree
+	}
+	_, qy, err := tapOutputKey(pubKey, root)
+	if err != nil {
+		return nil, err
+	}
+	p, _ := ParsePubKey(pubKey)
+	cb := append([]byte{ps[i].leaf.Version | byte(qy.Bit(0))}, p.XOnly()...)
+	return append(cb, ps[i].path...), nil
+}
+
+// TapOutput returns the X-only P2TR output key and the parity of its Y coordinate (0 even, 1 odd)
+// for the internal key pubKey (any format accepted by ParsePubKey) and the script tree t (nil for key path only).
+func TapOutput(pubKey []byte, t *TapTree) ([]byte, byte, error) {
+	var root []byte
+	if t != nil {
+		var err error
+		if root, err = t.MerkleRoot(); err != nil {
+			return nil, 0, err
+		}
+	}
+	qx, qy, err := tapOutputKey(pubKey, root)
+	if err != nil {
+		return nil, 0, err
+	}
+	return bytesFull(qx), byte(qy.Bit(0)), nil
+}
+
+// ScriptAddressP2TR returns the Pay-to-taproot address for the network n with the internal key pubKey
+// and the script tree t (nil for key path only, the same as GetAddressP2TR).
+func ScriptAddressP2TR(pubKey []byte, t *TapTree, n *Network) (string, error) {
+	if n.Bech32HRP == "" {
+		return "", NoSegwit
+	}
+	q, _, err := TapOutput(pubKey, t)
+	if err != nil {
+		return "", err
+	}
+	return Bech32mencode(q, n.Bech32HRP, 1), nil
+}
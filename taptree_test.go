@@ -0,0 +1,136 @@
+package cckat
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+// bip341Vectors are the scriptPubKey test vectors of BIP341 (wallet-test-vectors.json).
+type bip341Vectors struct {
+	ScriptPubKey []struct {
+		Given struct {
+			InternalPubkey string          `json:"internalPubkey"`
+			ScriptTree     json.RawMessage `json:"scriptTree"`
+		} `json:"given"`
+		Intermediary struct {
+			LeafHashes    []string `json:"leafHashes"`
+			MerkleRoot    string   `json:"merkleRoot"`
+			Tweak         string   `json:"tweak"`
+			TweakedPubkey string   `json:"tweakedPubkey"`
+		} `json:"intermediary"`
+		Expected struct {
+			ScriptPubKey  string   `json:"scriptPubKey"`
+			Address       string   `json:"bip350Address"`
+			ControlBlocks []string `json:"scriptPathControlBlocks"`
+		} `json:"expected"`
+	} `json:"scriptPubKey"`
+}
+
+// bip341Tree returns the script tree of the JSON encoded tree of the vectors: null, a leaf or an array
+// of two subtrees.
+func bip341Tree(t *testing.T, b json.RawMessage) *TapTree {
+	t.Helper()
+	var branch []json.RawMessage
+	if json.Unmarshal(b, &branch) == nil {
+		if branch == nil {
+			return nil
+		}
+		if len(branch) != 2 {
+			t.Fatalf("tree %s", b)
+		}
+		return &TapTree{Left: bip341Tree(t, branch[0]), Right: bip341Tree(t, branch[1])}
+	}
+	var l struct {
+		Script      string `json:"script"`
+		LeafVersion byte   `json:"leafVersion"`
+	}
+	if err := json.Unmarshal(b, &l); err != nil {
+		t.Fatal(err)
+	}
+	return &TapTree{Leaf: &TapLeaf{Version: l.LeafVersion, Script: mustHex(t, l.Script)}}
+}
+
+func TestTapTreeVectors(t *testing.T) {
+	b, err := os.ReadFile("testdata/bip341-vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v bip341Vectors
+	if err := json.Unmarshal(b, &v); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range v.ScriptPubKey {
+		key := mustHex(t, c.Given.InternalPubkey)
+		tree := bip341Tree(t, c.Given.ScriptTree)
+		var root []byte
+		if tree != nil {
+			leaves, err := tree.Leaves()
+			if err != nil || len(leaves) != len(c.Intermediary.LeafHashes) {
+				t.Errorf("%s: leaves %v, %v", c.Expected.Address, leaves, err)
+				continue
+			}
+			for i, l := range leaves {
+				if h := hex.EncodeToString(l.Hash()); h != c.Intermediary.LeafHashes[i] {
+					t.Errorf("%s: leaf %d hash %s, want %s", c.Expected.Address, i, h, c.Intermediary.LeafHashes[i])
+				}
+			}
+			if root, err = tree.MerkleRoot(); err != nil || hex.EncodeToString(root) != c.Intermediary.MerkleRoot {
+				t.Errorf("%s: merkle root %x, %v, want %s", c.Expected.Address, root, err, c.Intermediary.MerkleRoot)
+			}
+			if len(leaves) <= 2 {
+				// the same tree is built by NewTapTree
+				nt, err := NewTapTree(leaves...)
+				if r, _ := nt.MerkleRoot(); err != nil || hex.EncodeToString(r) != c.Intermediary.MerkleRoot {
+					t.Errorf("%s: NewTapTree root %x, %v", c.Expected.Address, r, err)
+				}
+			}
+			if len(leaves) == 2 {
+				lh := [][]byte{leaves[0].Hash(), leaves[1].Hash()}
+				if h := TapBranchHash(lh[1], lh[0]); hex.EncodeToString(h) != c.Intermediary.MerkleRoot {
+					t.Errorf("%s: TapBranchHash %x", c.Expected.Address, h)
+				}
+			}
+			for i, want := range c.Expected.ControlBlocks {
+				if cb, err := tree.ControlBlock(key, i); err != nil || hex.EncodeToString(cb) != want {
+					t.Errorf("%s: control block %d %x, %v, want %s", c.Expected.Address, i, cb, err, want)
+				}
+			}
+		}
+		if tw := taggedHash("TapTweak", concat(key, root)); hex.EncodeToString(tw) != c.Intermediary.Tweak {
+			t.Errorf("%s: tweak %x, want %s", c.Expected.Address, tw, c.Intermediary.Tweak)
+		}
+		q, _, err := TapOutput(key, tree)
+		if err != nil || hex.EncodeToString(q) != c.Intermediary.TweakedPubkey {
+			t.Errorf("%s: output key %x, %v, want %s", c.Expected.Address, q, err, c.Intermediary.TweakedPubkey)
+		}
+		if q, err := TapOutputKey(key, root); err != nil || hex.EncodeToString(witnessScript(1, q)) != c.Expected.ScriptPubKey {
+			t.Errorf("%s: TapOutputKey %x, %v", c.Expected.Address, q, err)
+		}
+		if a, err := ScriptAddressP2TR(key, tree, BTCMain); err != nil || a != c.Expected.Address {
+			t.Errorf("%s: address %s, %v", c.Expected.Address, a, err)
+		}
+	}
+}
+
+func TestTapTreeInvalid(t *testing.T) {
+	leaf := NewTapLeaf([]byte{0x51})
+	for name, tree := range map[string]*TapTree{
+		"empty":           {},
+		"odd version":     {Leaf: &TapLeaf{Version: 0xc1, Script: []byte{0x51}}},
+		"annex version":   {Leaf: &TapLeaf{Version: 0x50, Script: []byte{0x51}}},
+		"missing subtree": {Left: &TapTree{Leaf: &leaf}},
+	} {
+		if r, err := tree.MerkleRoot(); err == nil {
+			t.Errorf("%s: root %x", name, r)
+		}
+	}
+	if _, err := NewTapTree(); err != InvTapTree {
+		t.Errorf("NewTapTree without leaves: %v", err)
+	}
+	tree, _ := NewTapTree(leaf, leaf)
+	if _, err := tree.ControlBlock(mustHex(t, tapKey), 2); err != InvTapTree {
+		t.Errorf("control block of leaf 2: %v", err)
+	}
+}
@@ -0,0 +1,317 @@
+package cckat
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var (
+	InvTx       = errors.New("invalid transaction serialization")
+	InvOutPoint = errors.New("invalid outpoint")
+)
+
+// Tx is a Bitcoin transaction.
+type Tx struct {
+	Version  int32
+	TxIn     []*TxIn
+	TxOut    []*TxOut
+	LockTime uint32
+}
+
+// OutPoint is a reference to a transaction output. Hash is the transaction hash in the internal byte order
+// (reversed txid).
+type OutPoint struct {
+	Hash  [32]byte
+	Index uint32
+}
+
+// TxIn is a transaction input.
+type TxIn struct {
+	PrevOut   OutPoint
+	ScriptSig []byte
+	Sequence  uint32
+	Witness   [][]byte
+}
+
+// TxOut is a transaction output.
+type TxOut struct {
+	Value    int64
+	PkScript []byte
+}
+
+// HasWitness returns true if any input of tx has the witness.
+func (tx *Tx) HasWitness() bool {
+	for _, in := range tx.TxIn {
+		if len(in.Witness) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Serialize returns the serialization of tx, with the witness (BIP144) if tx has it.
+func (tx *Tx) Serialize() []byte {
+	return tx.serialize(tx.HasWitness())
+}
+
+// SerializeNoWitness returns the legacy serialization of tx (without the witness).
+func (tx *Tx) SerializeNoWitness() []byte {
+	return tx.serialize(false)
+}
+
+func (tx *Tx) serialize(witness bool) []byte {
+	b := binary.LittleEndian.AppendUint32(nil, uint32(tx.Version))
+	if witness {
+		b = append(b, 0x00, 0x01)
+	}
+	b = appendVarInt(b, uint64(len(tx.TxIn)))
+	for _, in := range tx.TxIn {
+		b = in.PrevOut.append(b)
+		b = appendVarBytes(b, in.ScriptSig)
+		b = binary.LittleEndian.AppendUint32(b, in.Sequence)
+	}
+	b = appendVarInt(b, uint64(len(tx.TxOut)))
+	for _, out := range tx.TxOut {
+		b = out.append(b)
+	}
+	if witness {
+		for _, in := range tx.TxIn {
+			b = appendWitness(b, in.Witness)
+		}
+	}
+	return binary.LittleEndian.AppendUint32(b, tx.LockTime)
+}
+
+// TxHash returns the double SHA256 of the legacy serialization of tx (the txid in the internal byte order).
+func (tx *Tx) TxHash() [32]byte {
+	return [32]byte(dsha256(tx.SerializeNoWitness()))
+}
+
+// WTxHash returns the double SHA256 of the serialization of tx with the witness (the wtxid in the internal
+// byte order). It is equal to TxHash if tx has no witness.
+func (tx *Tx) WTxHash() [32]byte {
+	return [32]byte(dsha256(tx.Serialize()))
+}
+
+// TxID returns the transaction ID (the reversed TxHash as hex string).
+func (tx *Tx) TxID() string {
+	return hashHex(tx.TxHash())
+}
+
+// WTxID returns the witness transaction ID (the reversed WTxHash as hex string).
+func (tx *Tx) WTxID() string {
+	return hashHex(tx.WTxHash())
+}
+
+// Hex returns Serialize as hex string.
+func (tx *Tx) Hex() string {
+	return hex.EncodeToString(tx.Serialize())
+}
+
+// Weight returns the weight of tx (BIP141): 3 * the size without the witness + the size with the witness.
+func (tx *Tx) Weight() int {
+	return 3*len(tx.SerializeNoWitness()) + len(tx.Serialize())
+}
+
+// VSize returns the virtual size of tx: Weight / 4 rounded up.
+func (tx *Tx) VSize() int {
+	return (tx.Weight() + 3) / 4
+}
+
+// hashHex returns the hex string of the reversed hash h (the display order of txid and block hashes).
+func hashHex(h [32]byte) string {
+	h = reverseHash(h)
+	return hex.EncodeToString(h[:])
+}
+
+func reverseHash(h [32]byte) [32]byte {
+	for i := range 16 {
+		h[i], h[31-i] = h[31-i], h[i]
+	}
+	return h
+}
+
+// String returns o as "txid:index".
+func (o OutPoint) String() string {
+	return hashHex(o.Hash) + ":" + strconv.FormatUint(uint64(o.Index), 10)
+}
+
+// ParseOutPoint parses the outpoint s in the "txid:index" format.
+func ParseOutPoint(s string) (OutPoint, error) {
+	txid, index, ok := strings.Cut(strings.TrimSpace(s), ":")
+	i, err := strconv.ParseUint(index, 10, 32)
+	if !ok || err != nil || len(txid) != 64 || !isHex(txid) {
+		return OutPoint{}, InvOutPoint
+	}
+	h, _ := hex.DecodeString(txid)
+	return OutPoint{Hash: reverseHash([32]byte(h)), Index: uint32(i)}, nil
+}
+
+func (o *OutPoint) append(b []byte) []byte {
+	b = append(b, o.Hash[:]...)
+	return binary.LittleEndian.AppendUint32(b, o.Index)
+}
+
+func (o *TxOut) append(b []byte) []byte {
+	b = binary.LittleEndian.AppendUint64(b, uint64(o.Value))
+	return appendVarBytes(b, o.PkScript)
+}
+
+// appendVarBytes appends the length of v (CompactSize) and v to b.
+func appendVarBytes(b, v []byte) []byte {
+	return append(appendVarInt(b, uint64(len(v))), v...)
+}
+
+// appendWitness appends the witness stack w to b.
+func appendWitness(b []byte, w [][]byte) []byte {
+	b = appendVarInt(b, uint64(len(w)))
+	for _, it := range w {
+		b = appendVarBytes(b, it)
+	}
+	return b
+}
+
+// ParseTx parses the serialized transaction b (with or without the witness). The input count 0 of the legacy
+// serialization looks like the witness marker: b is parsed as the legacy transaction without inputs if it is not
+// a valid witness serialization (as Bitcoin Core does).
+func ParseTx(b []byte) (*Tx, error) {
+	return parseTx(b, true)
+}
+
+// ParseTxHex parses the hex encoded serialized transaction s.
+func ParseTxHex(s string) (*Tx, error) {
+	s = strings.TrimSpace(s)
+	if !isHex(s) {
+		return nil, InvHexStr
+	}
+	b, _ := hex.DecodeString(s)
+	return ParseTx(b)
+}
+
+// parseTx parses the serialized transaction b, the witness serialization is not accepted if witness is false.
+func parseTx(b []byte, witness bool) (*Tx, error) {
+	r := &txReader{b: b}
+	tx := r.tx(witness)
+	if r.err != nil || len(r.b) != 0 {
+		if witness && len(b) > 4 && b[4] == 0x00 {
+			return parseTx(b, false)
+		}
+		return nil, InvTx
+	}
+	return tx, nil
+}
+
+// txReader reads the serialized data, the first error is kept in err.
+type txReader struct {
+	b   []byte
+	err error
+}
+
+func (r *txReader) bytes(n uint64) []byte {
+	if r.err != nil || uint64(len(r.b)) < n {
+		r.err = InvTx
+		return nil
+	}
+	v := r.b[:n:n]
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *txReader) u32() uint32 {
+	if v := r.bytes(4); v != nil {
+		return binary.LittleEndian.Uint32(v)
+	}
+	return 0
+}
+
+func (r *txReader) u64() uint64 {
+	if v := r.bytes(8); v != nil {
+		return binary.LittleEndian.Uint64(v)
+	}
+	return 0
+}
+
+// varInt reads the canonical CompactSize integer.
+func (r *txReader) varInt() uint64 {
+	v := r.bytes(1)
+	if v == nil {
+		return 0
+	}
+	var n, min uint64
+	switch v[0] {
+	case 0xfd:
+		if v = r.bytes(2); v != nil {
+			n, min = uint64(binary.LittleEndian.Uint16(v)), 0xfd
+		}
+	case 0xfe:
+		n, min = uint64(r.u32()), 0x10000
+	case 0xff:
+		n, min = r.u64(), 0x100000000
+	default:
+		return uint64(v[0])
+	}
+	if n < min {
+		r.err = InvTx
+	}
+	return n
+}
+
+func (r *txReader) varBytes() []byte {
+	return r.bytes(r.varInt())
+}
+
+// count reads the number of items each of them is at least size bytes long.
+func (r *txReader) count(size int) int {
+	n := r.varInt()
+	if n > uint64(len(r.b)/size) {
+		r.err = InvTx
+		return 0
+	}
+	return int(n)
+}
+
+func (r *txReader) witness() [][]byte {
+	w := make([][]byte, r.count(1))
+	for i := range w {
+		w[i] = r.varBytes()
+	}
+	return w
+}
+
+func (r *txReader) tx(allowWitness bool) *Tx {
+	tx := &Tx{Version: int32(r.u32())}
+	witness := allowWitness && len(r.b) > 1 && r.b[0] == 0x00
+	if witness {
+		if r.b[1] != 0x01 {
+			r.err = InvTx
+			return nil
+		}
+		r.b = r.b[2:]
+	}
+	tx.TxIn = make([]*TxIn, r.count(41))
+	for i := range tx.TxIn {
+		in := new(TxIn)
+		copy(in.PrevOut.Hash[:], r.bytes(32))
+		in.PrevOut.Index = r.u32()
+		in.ScriptSig = r.varBytes()
+		in.Sequence = r.u32()
+		tx.TxIn[i] = in
+	}
+	tx.TxOut = make([]*TxOut, r.count(9))
+	for i := range tx.TxOut {
+		tx.TxOut[i] = &TxOut{Value: int64(r.u64()), PkScript: r.varBytes()}
+	}
+	if witness {
+		for _, in := range tx.TxIn {
+			in.Witness = r.witness()
+		}
+		if !tx.HasWitness() {
+			r.err = InvTx
+		}
+	}
+	tx.LockTime = r.u32()
+	return tx
+}
@@ -0,0 +1,92 @@
+package cckat
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// The genesis coinbase, the native P2WPKH example of BIP143 and a transaction spending P2PKH, P2SH-P2WPKH,
+// P2WPKH and P2TR outputs (signed and verified by btcd).
+var txTests = []struct {
+	hex, txid, wtxid          string
+	weight, vsize, legacySize int
+}{
+	{"01000000010000000000000000000000000000000000000000000000000000000000000000ffffffff4d04ffff001d0104455468652054696d65732030332f4a616e2f32303039204368616e63656c6c6f72206f6e206272696e6b206f66207365636f6e64206261696c6f757420666f722062616e6b73ffffffff0100f2052a01000000434104678afdb0fe5548271967f1a67130b7105cd6a828e03909a67962e0ea1f61deb649f6bc3f4cef38c4f35504e51ec112de5c384df7ba0b8d578a4c702b6bf11d5fac00000000",
+		"4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b",
+		"4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b", 816, 204, 204},
+	{"01000000000102fff7f7881a8099afa6940d42d1e7f6362bec38171ea3edf433541db4e4ad969f00000000494830450221008b9d1dc26ba6a9cb62127b02742fa9d754cd3bebf337f7a55d114c8e5cdd30be022040529b194ba3f9281a99f2b1c0a19c0489bc22ede944ccf4ecbab4cc618ef3ed01eeffffffef51e1b804cc89d182d279655c3aa89e815b1b309fe287d9b2b55d57b90ec68a0100000000ffffffff02202cb206000000001976a9148280b37df378db99f66f85c95a783a76ac7a6d5988ac9093510d000000001976a9143bde42dbee7e4dbe6a21b2d50ce2f0167faa815988ac000247304402203609e17b84f6a7d30c80bfa610b5b4542f32a8a0d5447a12fb1366d7f01cc44a0220573a954c4518331561406f90300e8f3358f51928d43c212a8caed02de67eebee0121025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee635711000000",
+		"e8151a2af31c368a35053ddd4bdb285a8595c769a3ad83e0fa02314a602d4609",
+		"c36c38370907df2324d9ce9d149d191192f338b37665a82e78e76a12c909b762", 1042, 261, 233},
+	{"02000000000104f50f38f6430677094545effdbd9c6e17804d15c48565dfbd430e773566fb48ff000000006a47304402206053d7ee01a8c2c3d1760b3481171365fffefec29b7f82b10618a8675def72780220188167a5e81a24ced197c56e2e932cdedf30ac532d1e64acc1212acdba3c8764012103b8a5ffc85dfe7494777bcb1ae4790c9a0083e6ab0eb2faafe383f71ec9531c4dfffffffff50f38f6430677094545effdbd9c6e17804d15c48565dfbd430e773566fb48ff01000000171600147ab29539577f962f7370c67f5e8e81ee0bd72c16fffffffff50f38f6430677094545effdbd9c6e17804d15c48565dfbd430e773566fb48ff0200000000fffffffff50f38f6430677094545effdbd9c6e17804d15c48565dfbd430e773566fb48ff0300000000ffffffff01301b0f00000000001600147ab29539577f962f7370c67f5e8e81ee0bd72c160002483045022100d6990f1635736e129fd2877ebb17de440d2b34251c6bb0359e1002b7f6310e4a022040016f504f94163a2fbf426ac33bdd19ae5c2c96d6c473578f842247af2e1da0012103b8a5ffc85dfe7494777bcb1ae4790c9a0083e6ab0eb2faafe383f71ec9531c4d02473044022051f4107f25afb0b2ce1166e2a61412dcc55af4a85d434c9fab73fc344a0952fb022049400125b1e26e0aab5d9bc7b674c284175fd4f7b1d7fc2f36335c724f1c0b44012103b8a5ffc85dfe7494777bcb1ae4790c9a0083e6ab0eb2faafe383f71ec9531c4d01400cb722699dee3bfe3170b8274f8a806567e9d03c9bf872d410f16c4b82473ed2af8da4c64d5a73a997fda5a405afbdb2d33ed52ea95da4d392f4b5131079315200000000",
+		"92ad64c3d2be98139d9076fda9f481cc6ccfd8caab587cece88ffdc0797d31cc",
+		"b136024d68690f568641e58d838311857272921fb501c22cc8e4cdc99a6d5afd", 1620, 405, 334},
+}
+
+func TestTxVectors(t *testing.T) {
+	for _, c := range txTests {
+		tx, err := ParseTxHex(c.hex)
+		if err != nil {
+			t.Errorf("%s: %v", c.txid, err)
+			continue
+		}
+		if tx.Hex() != c.hex {
+			t.Errorf("%s: round trip %s", c.txid, tx.Hex())
+		}
+		if tx.TxID() != c.txid || tx.WTxID() != c.wtxid {
+			t.Errorf("%s: txid %s, wtxid %s, want %s", c.txid, tx.TxID(), tx.WTxID(), c.wtxid)
+		}
+		if tx.Weight() != c.weight || tx.VSize() != c.vsize {
+			t.Errorf("%s: weight %d, vsize %d, want %d, %d", c.txid, tx.Weight(), tx.VSize(), c.weight, c.vsize)
+		}
+		if tx.HasWitness() != (c.txid != c.wtxid) {
+			t.Errorf("%s: HasWitness %v", c.txid, tx.HasWitness())
+		}
+
+		// the legacy serialization
+		b := tx.SerializeNoWitness()
+		if len(b) != c.legacySize {
+			t.Errorf("%s: legacy size %d, want %d", c.txid, len(b), c.legacySize)
+		}
+		legacy, err := ParseTx(b)
+		if err != nil || legacy.HasWitness() || legacy.TxID() != c.txid || legacy.WTxID() != c.txid {
+			t.Errorf("%s: legacy serialization %v", c.txid, err)
+		}
+		if _, err := parseTx(mustHex(t, c.hex), false); (err == nil) != (c.txid == c.wtxid) {
+			t.Errorf("%s: parsed without witness: %v", c.txid, err)
+		}
+	}
+}
+
+// TestParseTxNoInputs parses the legacy transactions without inputs, the input count 0 is taken for
+// the witness marker first.
+func TestParseTxNoInputs(t *testing.T) {
+	out := "40420f00000000001600144dd193ac964a56ac1b9e1cca8454fe2f474f8513"
+	for _, n := range []int{1, 2} {
+		s := "0200000000" + hex.EncodeToString([]byte{byte(n)})
+		for range n {
+			s += out
+		}
+		s += "00000000"
+		tx, err := ParseTxHex(s)
+		if err != nil || len(tx.TxIn) != 0 || len(tx.TxOut) != n || tx.Hex() != s {
+			t.Errorf("%d outputs: %v, %v", n, tx, err)
+		}
+	}
+}
+
+func TestParseTxInvalid(t *testing.T) {
+	valid := txTests[1].hex
+	for name, s := range map[string]string{
+		"empty":               "",
+		"truncated":           valid[:len(valid)-2],
+		"trailing data":       valid + "00",
+		"witness flag 2":      valid[:10] + "02" + valid[12:],
+		"no witness":          "0100000000010100000000000000000000000000000000000000000000000000000000000000000000000000ffffffff010000000000000000000000000000",
+		"non-canonical count": "01000000fd0100" + valid[10:],
+		"input count":         "01000000ff" + valid[10:],
+	} {
+		if _, err := ParseTxHex(s); err == nil {
+			t.Errorf("%s: parsed", name)
+		}
+	}
+}